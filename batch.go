@@ -0,0 +1,189 @@
+package memcached_go
+
+import (
+	"bytes"
+	"context"
+	"memcached-go/mmc"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GetBatching configures coalescing of concurrent gets into multi-key gets, trading a little latency for far fewer
+// round trips when many goroutines read at the same time.
+type GetBatching struct {
+	// Window to wait for more keys, after the first key of a batch
+	Window time.Duration
+	// MaxKeys sends the batch right away once reached, DefaultGetBatching.MaxKeys if not set
+	MaxKeys int
+	// Timeout of the batch request, as it's not bound to the context of any of the callers, DefaultGetBatching.Timeout
+	// if not set
+	Timeout time.Duration
+}
+
+// DefaultGetBatching provides the defaults of unset fields of GetBatching, a zero Window is valid.
+var DefaultGetBatching = GetBatching{
+	MaxKeys: 100,
+	Timeout: time.Second,
+}
+
+// WithGetBatching makes Get and GetV coalesce concurrent calls, hedging is not applied to batched gets.
+func WithGetBatching(cfg GetBatching) Option {
+	if cfg.MaxKeys <= 0 {
+		cfg.MaxKeys = DefaultGetBatching.MaxKeys
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultGetBatching.Timeout
+	}
+	return func(c *Client) {
+		c.batcher = &getBatcher{cli: c, cfg: cfg}
+	}
+}
+
+// GetMulti fetches many keys in a single request, missing keys are not included in the result. Values are decoded and
+// unwrapped like with Get: chunks take another request each, and the versions of tags a single request for all values.
+// Invalidated tagged values and negative entries are misses.
+func (c *Client) GetMulti(ctx context.Context, keys []string) (map[string]mmc.Item, error) {
+	serverKeys := make([]string, len(keys))
+	for i, key := range keys {
+		var err error
+		serverKeys[i], err = c.key(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	items, err := c.getMulti(ctx, serverKeys)
+	if err != nil {
+		return nil, err
+	}
+	for key, item := range items {
+		val, flags, err := c.decode(ctx, key, item.Value, item.Flags)
+		if err != nil {
+			return nil, err
+		}
+		if val == nil {
+			delete(items, key)
+			continue
+		}
+		items[key] = mmc.Item{Flags: flags, Value: val}
+	}
+	var versions map[string]uint64
+	if c.tagging != nil {
+		versions, err = c.multiTagVersions(ctx, items)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for key, item := range items {
+		val, flags, err := c.unwrap(ctx, key, item.Value, item.Flags, versions)
+		if err != nil {
+			return nil, err
+		}
+		if val == nil {
+			delete(items, key)
+			continue
+		}
+		items[key] = mmc.Item{Flags: flags, Value: val}
+	}
+	if c.keyTransformer == nil {
+		return items, nil
+	}
+	// Back to the caller's keys
+	byKey := make(map[string]mmc.Item, len(items))
+	for i, key := range keys {
+		if item, ok := items[serverKeys[i]]; ok {
+			byKey[key] = item
+		}
+	}
+	return byKey, nil
+}
+
+func (c *Client) getMulti(ctx context.Context, keys []string) (map[string]mmc.Item, error) {
+	msg := mmc.NewMultiGet(keys)
+	err := c.call(ctx, OpGet, strings.Join(keys, " "), msg)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Error != nil {
+		return nil, msg.Error
+	}
+	return msg.Items, nil
+}
+
+type getBatcher struct {
+	cli *Client
+	cfg GetBatching
+
+	lock  sync.Mutex
+	batch *getBatch
+}
+
+type getBatch struct {
+	keys []string
+	// Number of callers waiting for each key
+	waiters map[string]int
+	timer   *time.Timer
+
+	// Response, valid once done is closed
+	done  chan struct{}
+	items map[string]mmc.Item
+	err   error
+}
+
+func (b *getBatcher) get(ctx context.Context, key string) (mmc.Item, bool, error) {
+	b.lock.Lock()
+	batch := b.batch
+	if batch == nil {
+		batch = &getBatch{waiters: map[string]int{}, done: make(chan struct{})}
+		batch.timer = time.AfterFunc(b.cfg.Window, func() { b.flush(batch) })
+		b.batch = batch
+	}
+	if batch.waiters[key] == 0 {
+		batch.keys = append(batch.keys, key)
+	}
+	batch.waiters[key]++
+	if len(batch.keys) >= b.cfg.MaxKeys {
+		b.batch = nil
+		batch.timer.Stop()
+		go b.send(batch)
+	}
+	b.lock.Unlock()
+
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return mmc.Item{}, false, ctx.Err()
+	}
+
+	if batch.err != nil {
+		return mmc.Item{}, false, batch.err
+	}
+	item, ok := batch.items[key]
+	if ok && batch.waiters[key] > 1 {
+		// Callers must be able to modify the value they got, so it's not shared
+		item.Value = bytes.Clone(item.Value)
+	}
+	return item, ok, nil
+}
+
+func (b *getBatcher) flush(batch *getBatch) {
+	b.lock.Lock()
+	if b.batch != batch {
+		// Already sent, as it was full
+		b.lock.Unlock()
+		return
+	}
+	b.batch = nil
+	b.lock.Unlock()
+
+	b.send(batch)
+}
+
+func (b *getBatcher) send(batch *getBatch) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.cfg.Timeout)
+	defer cancel()
+
+	batch.items, batch.err = b.cli.getMulti(ctx, batch.keys)
+	close(batch.done)
+}
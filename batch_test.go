@@ -0,0 +1,154 @@
+package memcached_go
+
+import (
+	"context"
+	"fmt"
+	"memcached-go/testutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type BatchSuite struct {
+	testutil.BaseSuite
+
+	fake *testutil.FakeMemcached
+}
+
+func TestBatchSuite(t *testing.T) {
+	suite.Run(t, new(BatchSuite))
+}
+
+func (s *BatchSuite) SetupTest() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	s.fake = fake
+
+	for i := 0; i < 10; i++ {
+		s.fake.Put(fmt.Sprintf("key-%d", i), uint32(i), []byte(fmt.Sprintf("value-%d", i)))
+	}
+}
+
+func (s *BatchSuite) TearDownTest() {
+	s.fake.Close()
+}
+
+func (s *BatchSuite) TestGetMulti() {
+	cli, err := NewClient(s.fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	items, err := cli.GetMulti(context.Background(), []string{"key-1", "missing", "key-2"})
+	s.Require().NoError(err)
+	s.Len(items, 2)
+	s.Equal("value-1", string(items["key-1"].Value))
+	s.Equal(uint16(1), items["key-1"].Flags)
+	s.Equal("value-2", string(items["key-2"].Value))
+}
+
+func (s *BatchSuite) TestGetMultiUnwrap() {
+	cli, err := NewClient(s.fake.Addr(), 1, 1,
+		WithTagging(Tagging{}), WithNegativeCaching(NegativeCaching{}), WithEarlyRefresh(EarlyRefresh{}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	s.Require().NoError(cli.SetTagged(ctx, "tagged", 3, []byte("tagged"), 0, "a", "b"))
+	s.Require().NoError(cli.SetTagged(ctx, "invalidated", 0, []byte("invalidated"), 0, "b", "c"))
+	s.Require().NoError(cli.InvalidateTag(ctx, "c"))
+	_, err = cli.GetOrLoad(ctx, "negative", time.Hour, func(ctx context.Context) ([]byte, error) {
+		return nil, ErrNotFound
+	})
+	s.Require().ErrorIs(err, ErrNotFound)
+	_, err = cli.GetOrLoad(ctx, "enveloped", time.Hour, func(ctx context.Context) ([]byte, error) {
+		return []byte("loaded"), nil
+	})
+	s.Require().NoError(err)
+
+	before := s.fake.Commands()
+	items, err := cli.GetMulti(ctx, []string{"tagged", "invalidated", "negative", "enveloped", "key-1"})
+	s.Require().NoError(err)
+	// The values, and then the versions of all tags
+	s.Equal(2, s.fake.Commands()-before)
+	s.Len(items, 3)
+	s.Equal("tagged", string(items["tagged"].Value))
+	s.Equal(uint16(3), items["tagged"].Flags)
+	s.Equal("loaded", string(items["enveloped"].Value))
+	s.Equal(uint16(0), items["enveloped"].Flags)
+	s.Equal("value-1", string(items["key-1"].Value))
+}
+
+func (s *BatchSuite) TestCoalescing() {
+	cli, err := NewClient(s.fake.Addr(), 1, 1, WithGetBatching(GetBatching{Window: 20 * time.Millisecond, MaxKeys: 1000}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	workers := 50
+	wg := &sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func(worker int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key-%d", worker%12)
+			val, flags, err := cli.Get(context.Background(), key)
+			s.NoError(err)
+			if worker%12 < 10 {
+				s.Equal(fmt.Sprintf("value-%d", worker%12), string(val))
+				s.Equal(uint16(worker%12), flags)
+			} else {
+				s.Nil(val)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Usually a single multi-get, but the scheduler may delay some of the workers past the window
+	s.Less(s.fake.Commands(), 5)
+}
+
+func (s *BatchSuite) TestMaxKeys() {
+	cli, err := NewClient(s.fake.Addr(), 1, 1, WithGetBatching(GetBatching{Window: time.Hour, MaxKeys: 3}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+	for i := 0; i < 3; i++ {
+		go func(i int) {
+			defer wg.Done()
+			val, err := cli.GetV(context.Background(), fmt.Sprintf("key-%d", i))
+			s.NoError(err)
+			s.Equal(fmt.Sprintf("value-%d", i), string(val))
+		}(i)
+	}
+	// Sent once full, without waiting for the window
+	wg.Wait()
+	s.Equal(1, s.fake.Commands())
+}
+
+func (s *BatchSuite) TestCanceled() {
+	cli, err := NewClient(s.fake.Addr(), 1, 1, WithGetBatching(GetBatching{Window: time.Hour, MaxKeys: 100}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = cli.GetV(ctx, "key-1")
+	s.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func (s *BatchSuite) TestDefaults() {
+	cli, err := NewClient(s.fake.Addr(), 1, 1, WithGetBatching(GetBatching{}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	s.Equal(DefaultGetBatching.MaxKeys, cli.batcher.cfg.MaxKeys)
+	s.Equal(DefaultGetBatching.Timeout, cli.batcher.cfg.Timeout)
+
+	s.fake.Put("foo", 0, []byte("bar"))
+	val, err := cli.GetV(context.Background(), "foo")
+	s.Require().NoError(err)
+	s.Equal("bar", string(val))
+}
@@ -0,0 +1,78 @@
+package memcached_go
+
+import (
+	"context"
+	"errors"
+	"memcached-go/mmc"
+	"time"
+)
+
+// Gets returns the value with its CAS unique, for a later CompareAndSwap or CompareAndDelete. The value is returned
+// as stored, without decompression or joining of chunks, and never from the near cache. A miss is a nil value.
+func (c *Client) Gets(ctx context.Context, key string) ([]byte, uint16, uint64, error) {
+	key, err := c.key(key)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	getsMsg := mmc.NewGets(key)
+	err = c.call(ctx, OpGets, key, getsMsg)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if errors.Is(getsMsg.Error, mmc.ErrMiss) {
+		return nil, 0, 0, nil
+	}
+	if getsMsg.Error != nil {
+		return nil, 0, 0, getsMsg.Error
+	}
+	return getsMsg.Value, getsMsg.Flags, getsMsg.Cas, nil
+}
+
+// CompareAndSwap stores the value only if it wasn't modified since Gets returned cas. Otherwise it fails with
+// mmc.ErrExists, or with mmc.ErrNotFound if the key is gone. Values are stored as is, like by Add.
+func (c *Client) CompareAndSwap(
+	ctx context.Context, key string, flags uint16, val []byte, ttl time.Duration, cas uint64,
+) error {
+	key, err := c.key(key)
+	if err != nil {
+		return err
+	}
+	if flags&c.reservedFlags != 0 {
+		return ErrReservedFlags
+	}
+
+	casMsg := mmc.NewCas(key, flags, val, ttl, cas)
+	defer c.invalidateNear(key)
+	err = c.call(ctx, OpCas, key, casMsg)
+	if err != nil {
+		return err
+	}
+	if casMsg.Error != nil {
+		return casMsg.Error
+	}
+	return nil
+}
+
+// CompareAndDelete deletes the key only if it wasn't modified since Gets returned cas, otherwise it fails with
+// mmc.ErrExists. Like Delete, deleting a missing key is not an error.
+func (c *Client) CompareAndDelete(ctx context.Context, key string, cas uint64) error {
+	key, err := c.key(key)
+	if err != nil {
+		return err
+	}
+
+	// The text protocol delete can't compare, the meta one can
+	deleteOp := mmc.NewMetaDelete(key)
+	deleteOp.Cas = cas
+	defer c.invalidateNear(key)
+	msg := mmc.NewPipeline([]mmc.MetaOp{deleteOp})
+	err = c.call(ctx, OpCas, key, msg)
+	if err != nil {
+		return err
+	}
+	if msg.Error != nil {
+		return msg.Error
+	}
+	return msg.Failures[0]
+}
@@ -0,0 +1,168 @@
+package memcached_go
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"math/rand/v2"
+	"memcached-go/mmc"
+	"time"
+)
+
+// FlagChunked marks a manifest of a chunked value by default, see Chunking.Flag.
+const FlagChunked uint16 = 1 << 15
+
+// DefaultChunkSize leaves room for the key and the item header below memcached's default 1MB item size limit.
+const DefaultChunkSize = 1<<20 - 4096
+
+// DefaultMaxChunkedSize bounds chunked values by default, so that a corrupt or foreign manifest can't trigger a huge
+// allocation.
+const DefaultMaxChunkedSize = 64 << 20
+
+// maxChunks bounds the number of chunks of a value, which are fetched with a single request
+const maxChunks = 4096
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Chunking configures storage of values larger than the server's item size limit. Such values are split into chunks,
+// stored under separate keys, and a manifest is stored under the key itself, once all chunks are written.
+// The manifest holds a generation id, which is part of the chunk keys, so that readers never stitch together chunks of
+// different writes, and a checksum of the whole value. Chunks of overwritten values are left to expire or be evicted.
+type Chunking struct {
+	// ChunkSize is the largest value stored as is, DefaultChunkSize if zero
+	ChunkSize int
+	// Flag is the reserved bit marking manifests, FlagChunked if zero, see Compression.Flag for the bits allowed
+	Flag uint16
+	// MaxSize of chunked values, larger ones fail with ErrTooLarge. DefaultMaxChunkedSize if zero. Values also fail if
+	// they'd be split into more than 4096 chunks.
+	MaxSize int
+}
+
+// WithChunking makes Set and Get transparently split and join large values. Flags passed to Set must not use the
+// reserved bit.
+func WithChunking(cfg Chunking) Option {
+	return func(c *Client) {
+		if cfg.ChunkSize <= 0 {
+			cfg.ChunkSize = DefaultChunkSize
+		}
+		if cfg.Flag == 0 {
+			cfg.Flag = FlagChunked
+		}
+		if cfg.MaxSize <= 0 {
+			cfg.MaxSize = DefaultMaxChunkedSize
+		}
+		c.chunking = &cfg
+		c.reserveFlag("chunking", cfg.Flag)
+	}
+}
+
+// manifest describes a chunked value.
+type manifest struct {
+	generation uint64
+	size       int
+	chunkSize  int
+	checksum   uint32
+}
+
+func (m manifest) encode() []byte {
+	return fmt.Appendf(nil, "%016x %d %d %08x", m.generation, m.size, m.chunkSize, m.checksum)
+}
+
+// decodeManifest parses a manifest, and checks that the value is at most maxSize bytes in at most maxChunks chunks.
+func decodeManifest(val []byte, maxSize int) (manifest, error) {
+	var m manifest
+	_, err := fmt.Sscanf(string(val), "%x %d %d %x", &m.generation, &m.size, &m.chunkSize, &m.checksum)
+	if err != nil || m.size < 0 || m.chunkSize <= 0 {
+		return manifest{}, fmt.Errorf("invalid chunk manifest %q", string(val))
+	}
+	if m.size > maxSize || m.chunks() > maxChunks {
+		return manifest{}, fmt.Errorf("chunk manifest %q: %w", string(val), ErrTooLarge)
+	}
+	return m, nil
+}
+
+func (m manifest) chunks() int {
+	// Without overflowing for huge chunk sizes
+	return m.size/m.chunkSize + min(m.size%m.chunkSize, 1)
+}
+
+func (m manifest) chunkKey(key string, i int) string {
+	suffix := fmt.Sprintf(":%016x:%d", m.generation, i)
+	if len(key)+len(suffix) > mmc.MaxKeyLength {
+		// The generation keeps the chunk keys unique to the write anyway
+		sum := sha256.Sum256([]byte(key))
+		key = hex.EncodeToString(sum[:])
+	}
+	return key + suffix
+}
+
+// setChunked writes the chunks in a single pipeline, then the manifest, which makes the new value visible.
+func (c *Client) setChunked(ctx context.Context, key string, flags uint16, val []byte, ttl time.Duration) error {
+	m := manifest{
+		generation: rand.Uint64(),
+		size:       len(val),
+		chunkSize:  c.chunking.ChunkSize,
+		checksum:   crc32.Checksum(val, crcTable),
+	}
+	if m.size > c.chunking.MaxSize || m.chunks() > maxChunks {
+		return fmt.Errorf("set %s: %w", key, ErrTooLarge)
+	}
+
+	chunks := make([]mmc.MetaOp, m.chunks())
+	for i := range chunks {
+		chunk := val[i*m.chunkSize : min((i+1)*m.chunkSize, len(val))]
+		chunks[i] = mmc.NewMetaSet(m.chunkKey(key, i), 0, chunk, ttl)
+	}
+	failures, err := c.execPipeline(ctx, chunks)
+	if err != nil {
+		return err
+	}
+	for i := range chunks {
+		if failure := failures[i]; failure != nil {
+			return fmt.Errorf("set chunk %d of %s: %w", i, key, failure)
+		}
+	}
+
+	setMsg := mmc.NewSet(key, flags|c.chunking.Flag, m.encode(), ttl)
+	err = c.call(ctx, OpSet, key, setMsg)
+	if err != nil {
+		return err
+	}
+	if setMsg.Error != nil {
+		return setMsg.Error
+	}
+	return nil
+}
+
+// getChunked fetches the chunks listed in the manifest, a value with missing chunks is a miss.
+func (c *Client) getChunked(ctx context.Context, key string, val []byte, flags uint16) ([]byte, uint16, error) {
+	m, err := decodeManifest(val, c.chunking.MaxSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get %s: %w", key, err)
+	}
+
+	keys := make([]string, m.chunks())
+	for i := range keys {
+		keys[i] = m.chunkKey(key, i)
+	}
+	items, err := c.getMulti(ctx, keys)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	joined := make([]byte, 0, m.size)
+	for _, chunkKey := range keys {
+		item, ok := items[chunkKey]
+		if !ok {
+			// Evicted or expired
+			return nil, 0, nil
+		}
+		joined = append(joined, item.Value...)
+	}
+	if len(joined) != m.size || crc32.Checksum(joined, crcTable) != m.checksum {
+		return nil, 0, fmt.Errorf("get %s: %w", key, ErrChecksum)
+	}
+	return joined, flags &^ c.chunking.Flag, nil
+}
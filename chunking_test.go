@@ -0,0 +1,124 @@
+package memcached_go
+
+import (
+	"bytes"
+	"context"
+	"memcached-go/testutil"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ChunkingSuite struct {
+	testutil.BaseSuite
+}
+
+func TestChunkingSuite(t *testing.T) {
+	suite.Run(t, new(ChunkingSuite))
+}
+
+func (s *ChunkingSuite) TestChunking() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1, WithChunking(Chunking{ChunkSize: 1000}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	large := bytes.Repeat([]byte("0123456789"), 350)
+	s.Require().NoError(cli.Set(ctx, "large", 5, large, 0))
+
+	stored, flags, ok := fake.Get("large")
+	s.Require().True(ok)
+	s.Equal(uint32(5|FlagChunked), flags)
+	m, err := decodeManifest(stored, DefaultMaxChunkedSize)
+	s.Require().NoError(err)
+	s.Equal(4, m.chunks())
+
+	val, readFlags, err := cli.Get(ctx, "large")
+	s.Require().NoError(err)
+	s.Equal(large, val)
+	s.Equal(uint16(5), readFlags)
+
+	// Overwritten with chunks of a new generation
+	larger := bytes.Repeat([]byte("abcdefghij"), 420)
+	s.Require().NoError(cli.Set(ctx, "large", 5, larger, 0))
+	val, err = cli.GetV(ctx, "large")
+	s.Require().NoError(err)
+	s.Equal(larger, val)
+
+	// Small values are stored as is
+	s.Require().NoError(cli.Set(ctx, "small", 1, []byte("small"), 0))
+	_, flags, _ = fake.Get("small")
+	s.Equal(uint32(1), flags)
+	val, err = cli.GetV(ctx, "small")
+	s.Require().NoError(err)
+	s.Equal("small", string(val))
+
+	s.ErrorIs(cli.Set(ctx, "small", FlagChunked, []byte("small"), 0), ErrReservedFlags)
+}
+
+func (s *ChunkingSuite) TestIncomplete() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1, WithChunking(Chunking{ChunkSize: 1000}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	large := bytes.Repeat([]byte("0123456789"), 350)
+	s.Require().NoError(cli.Set(ctx, "large", 0, large, 0))
+	stored, _, _ := fake.Get("large")
+	m, err := decodeManifest(stored, DefaultMaxChunkedSize)
+	s.Require().NoError(err)
+
+	// Corrupted chunk
+	fake.Put(m.chunkKey("large", 1), 0, bytes.Repeat([]byte("x"), 1000))
+	_, err = cli.GetV(ctx, "large")
+	s.ErrorIs(err, ErrChecksum)
+
+	// Evicted chunk
+	s.Require().NoError(cli.Delete(ctx, m.chunkKey("large", 2)))
+	val, err := cli.GetV(ctx, "large")
+	s.Require().NoError(err)
+	s.Nil(val)
+}
+
+func (s *ChunkingSuite) TestLimits() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1, WithChunking(Chunking{ChunkSize: 1000, MaxSize: 10_000}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	s.ErrorIs(cli.Set(ctx, "large", 0, make([]byte, 10_001), 0), ErrTooLarge)
+	_, _, ok := fake.Get("large")
+	s.False(ok)
+
+	// Corrupt or foreign manifests
+	for _, m := range []string{
+		"0000000000000001 10001 1000 00000000",
+		"0000000000000001 9223372036854775807 1000 00000000",
+		"0000000000000001 10000 1 00000000",
+		"0000000000000001 -1 1000 00000000",
+		"0000000000000001 10000 0 00000000",
+	} {
+		fake.Put("corrupt", uint32(FlagChunked), []byte(m))
+		val, err := cli.GetV(ctx, "corrupt")
+		s.Error(err, m)
+		s.Nil(val, m)
+	}
+
+	// A single chunk, which is missing
+	fake.Put("corrupt", uint32(FlagChunked), []byte("0000000000000001 10000 9223372036854775807 00000000"))
+	val, err := cli.GetV(ctx, "corrupt")
+	s.NoError(err)
+	s.Nil(val)
+}
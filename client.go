@@ -3,21 +3,86 @@ package memcached_go
 import (
 	"context"
 	"errors"
+	"fmt"
 	"memcached-go/gonet"
+	"memcached-go/internal/singleflight"
 	"memcached-go/mmc"
 	"time"
 )
 
 type Client struct {
 	cli *gonet.Client
+
+	connOpts       []gonet.ClientOption
+	retry          *RetryPolicy
+	hedge          gonet.HedgePolicy
+	batcher        *getBatcher
+	chunking       *Chunking
+	compression    *Compression
+	keyTransformer KeyTransformer
+	near           *nearCache
+	negative       *NegativeCaching
+	refresh        *EarlyRefresh
+	tagging        *Tagging
+	flights        singleflight.Group[[]byte]
+	hooks          []Hook
+
+	nearStats   tierCounters
+	serverStats tierCounters
+
+	// Flag bits used by the client, which callers must not set
+	reservedFlags uint16
+	// First invalid option, returned by NewClient
+	optErr error
+}
+
+// Option customizes the Client created with NewClient.
+type Option func(c *Client)
+
+// WithConnOptions passes options to the underlying connection pool.
+func WithConnOptions(opts ...gonet.ClientOption) Option {
+	return func(c *Client) {
+		c.connOpts = append(c.connOpts, opts...)
+	}
 }
 
-func NewClient(addr string, minConns, maxConns int) (*Client, error) {
-	cli, err := gonet.NewClient(addr, minConns, maxConns)
+// WithHedging sends a second get on another connection when the first one is slow, see gonet.Client.CallHedged.
+func WithHedging(policy gonet.HedgePolicy) Option {
+	return func(c *Client) {
+		c.hedge = policy
+	}
+}
+
+// reserveFlag marks the bit of a feature as used by the client. Features must not share bits, nor use the low byte,
+// which holds the codec ID of Typed values.
+func (c *Client) reserveFlag(feature string, flag uint16) {
+	var err error
+	switch {
+	case flag&codecFlags != 0:
+		err = fmt.Errorf("%s flag %#x overlaps the codec ID byte: %w", feature, flag, ErrFlagConflict)
+	case flag&c.reservedFlags != 0:
+		err = fmt.Errorf("%s flag %#x: %w", feature, flag, ErrFlagConflict)
+	}
+	if err != nil && c.optErr == nil {
+		c.optErr = err
+	}
+	c.reservedFlags |= flag
+}
+
+func NewClient(addr string, minConns, maxConns int, opts ...Option) (*Client, error) {
+	c := &Client{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.optErr != nil {
+		return nil, c.optErr
+	}
+	cli, err := gonet.NewClient(addr, minConns, maxConns, c.connOpts...)
 	if err != nil {
 		return nil, err
 	}
-	return &Client{cli: cli}, nil
+	c.cli = cli
+	return c, nil
 }
 
 func (c *Client) Close() {
@@ -25,8 +90,104 @@ func (c *Client) Close() {
 }
 
 func (c *Client) Get(ctx context.Context, key string) ([]byte, uint16, error) {
-	getMsg := mmc.NewGet(key)
-	err := c.cli.Call(ctx, getMsg)
+	key, err := c.key(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	val, flags, err := c.lookup(ctx, key)
+	if err != nil || val == nil {
+		return nil, 0, err
+	}
+	return c.unwrap(ctx, key, val, flags, nil)
+}
+
+// unwrap validates the tags of a tagged value, and unwraps values stored by GetOrLoad. Versions of tags fetched already
+// are optional.
+func (c *Client) unwrap(
+	ctx context.Context, key string, val []byte, flags uint16, versions map[string]uint64,
+) ([]byte, uint16, error) {
+	var err error
+	if c.tagging != nil && flags&c.tagging.Flag != 0 {
+		var valid bool
+		val, valid, err = c.checkTags(ctx, key, val, versions)
+		if err != nil || !valid {
+			return nil, 0, err
+		}
+		flags &^= c.tagging.Flag
+	}
+	if c.negative != nil && flags&c.negative.Flag != 0 {
+		// Negative entries are misses for plain gets
+		return nil, 0, nil
+	}
+	if c.refresh != nil && flags&c.refresh.Flag != 0 {
+		_, val, err = unwrapEnvelope(val)
+		if err != nil {
+			return nil, 0, fmt.Errorf("get %s: %w", key, err)
+		}
+		flags &^= c.refresh.Flag
+	}
+	return val, flags, err
+}
+
+// lookup gets the value from the near cache or from the server.
+func (c *Client) lookup(ctx context.Context, key string) ([]byte, uint16, error) {
+	if c.near == nil {
+		return c.get(ctx, key)
+	}
+
+	if val, flags, ok := c.near.get(key); ok {
+		c.nearStats.record(true)
+		return val, flags, nil
+	}
+	c.nearStats.record(false)
+	version := c.near.version()
+	val, flags, err := c.get(ctx, key)
+	if err == nil && val != nil {
+		c.near.fill(key, val, flags, version)
+	}
+	return val, flags, err
+}
+
+// get gets the value from the server, and decodes it.
+func (c *Client) get(ctx context.Context, key string) ([]byte, uint16, error) {
+	val, flags, err := c.getValue(ctx, key)
+	c.serverStats.record(err == nil && val != nil)
+	if err != nil || val == nil {
+		return nil, 0, err
+	}
+	return c.decode(ctx, key, val, flags)
+}
+
+// decode joins a chunked value and decompresses a compressed one, returns nil if chunks are missing.
+func (c *Client) decode(ctx context.Context, key string, val []byte, flags uint16) ([]byte, uint16, error) {
+	var err error
+	if c.chunking != nil && flags&c.chunking.Flag != 0 {
+		val, flags, err = c.getChunked(ctx, key, val, flags)
+		if err != nil || val == nil {
+			return nil, 0, err
+		}
+	}
+	if c.compression != nil && flags&c.compression.Flag != 0 {
+		val, err = c.compression.Compressor.Decompress(val)
+		if err != nil {
+			return nil, 0, fmt.Errorf("decompress %s: %w", key, err)
+		}
+		flags &^= c.compression.Flag
+	}
+	return val, flags, nil
+}
+
+// getValue gets the value as stored, it may be a manifest of a chunked value.
+func (c *Client) getValue(ctx context.Context, key string) ([]byte, uint16, error) {
+	if c.batcher != nil {
+		item, ok, err := c.batcher.get(ctx, key)
+		if err != nil || !ok {
+			return nil, 0, err
+		}
+		return item.Value, item.Flags, nil
+	}
+
+	getMsg, err := c.getMsg(ctx, key)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -36,14 +197,68 @@ func (c *Client) Get(ctx context.Context, key string) ([]byte, uint16, error) {
 	return getMsg.Value, getMsg.Flags, nil
 }
 
+func (c *Client) getMsg(ctx context.Context, key string) (*mmc.Get, error) {
+	if c.hedge == nil {
+		getMsg := mmc.NewGet(key)
+		return getMsg, c.call(ctx, OpGet, key, getMsg)
+	}
+
+	var getMsg *mmc.Get
+	err := c.attempt(ctx, OpGet, key, func() error {
+		msg, err := c.cli.CallHedged(ctx, c.hedge, func() gonet.Message { return mmc.NewGet(key) })
+		if err != nil {
+			return err
+		}
+		getMsg = msg.(*mmc.Get)
+		return nil
+	})
+	return getMsg, err
+}
+
 func (c *Client) GetV(ctx context.Context, key string) ([]byte, error) {
 	val, _, err := c.Get(ctx, key)
 	return val, err
 }
 
 func (c *Client) Set(ctx context.Context, key string, flags uint16, val []byte, ttl time.Duration) error {
+	key, err := c.key(key)
+	if err != nil {
+		return err
+	}
+	if flags&c.reservedFlags != 0 {
+		return ErrReservedFlags
+	}
+	return c.store(ctx, key, flags, val, ttl)
+}
+
+// store sets the value on the server and in the near cache.
+func (c *Client) store(ctx context.Context, key string, flags uint16, val []byte, ttl time.Duration) error {
+	err := c.set(ctx, key, flags, val, ttl)
+	if c.near != nil {
+		if err == nil {
+			c.near.set(key, val, flags, ttl)
+		} else {
+			c.near.invalidate(key)
+		}
+	}
+	return err
+}
+
+// set encodes the value, and stores it on the server.
+func (c *Client) set(ctx context.Context, key string, flags uint16, val []byte, ttl time.Duration) error {
+	var err error
+	if c.compression != nil {
+		val, flags, err = c.compress(val, flags)
+		if err != nil {
+			return fmt.Errorf("compress %s: %w", key, err)
+		}
+	}
+	if c.chunking != nil && len(val) > c.chunking.ChunkSize {
+		return c.setChunked(ctx, key, flags, val, ttl)
+	}
+
 	setMsg := mmc.NewSet(key, flags, val, ttl)
-	err := c.cli.Call(ctx, setMsg)
+	err = c.call(ctx, OpSet, key, setMsg)
 	if err != nil {
 		return err
 	}
@@ -56,3 +271,123 @@ func (c *Client) Set(ctx context.Context, key string, flags uint16, val []byte,
 func (c *Client) SetV(ctx context.Context, key string, val []byte) error {
 	return c.Set(ctx, key, 0, val, 0)
 }
+
+// SetAsync sends a set with noreply, returning as soon as the request has been written. Errors reported by the server
+// are not visible to the caller. Values are stored as is, without compression or chunking.
+func (c *Client) SetAsync(ctx context.Context, key string, flags uint16, val []byte, ttl time.Duration) error {
+	key, err := c.key(key)
+	if err != nil {
+		return err
+	}
+	if flags&c.reservedFlags != 0 {
+		return ErrReservedFlags
+	}
+	setMsg := mmc.NewSet(key, flags, val, ttl)
+	setMsg.NoReply = true
+	defer c.invalidateNear(key)
+	return c.call(ctx, OpSet, key, setMsg)
+}
+
+// Add stores the value only if the key doesn't exist yet, otherwise it fails with mmc.ErrNotStored. Values are stored as
+// is, without compression or chunking.
+func (c *Client) Add(ctx context.Context, key string, flags uint16, val []byte, ttl time.Duration) error {
+	key, err := c.key(key)
+	if err != nil {
+		return err
+	}
+	if flags&c.reservedFlags != 0 {
+		return ErrReservedFlags
+	}
+
+	addMsg := mmc.NewAdd(key, flags, val, ttl)
+	defer c.invalidateNear(key)
+	err = c.call(ctx, OpAdd, key, addMsg)
+	if err != nil {
+		return err
+	}
+	if addMsg.Error != nil {
+		return addMsg.Error
+	}
+	return nil
+}
+
+// Incr increments a counter and returns the new value. A missing key fails with mmc.ErrNotFound, counters have to
+// be created, e.g. with Add.
+func (c *Client) Incr(ctx context.Context, key string, delta uint64) (uint64, error) {
+	return c.incr(ctx, OpIncr, key, mmc.NewIncr, delta)
+}
+
+// Decr decrements a counter and returns the new value, which doesn't go below zero.
+func (c *Client) Decr(ctx context.Context, key string, delta uint64) (uint64, error) {
+	return c.incr(ctx, OpDecr, key, mmc.NewDecr, delta)
+}
+
+func (c *Client) incr(
+	ctx context.Context, op, key string, newMsg func(key string, delta uint64) *mmc.Incr, delta uint64,
+) (uint64, error) {
+	key, err := c.key(key)
+	if err != nil {
+		return 0, err
+	}
+
+	incrMsg := newMsg(key, delta)
+	defer c.invalidateNear(key)
+	err = c.call(ctx, op, key, incrMsg)
+	if err != nil {
+		return 0, err
+	}
+	if incrMsg.Error != nil {
+		return 0, incrMsg.Error
+	}
+	return incrMsg.Value, nil
+}
+
+// Touch updates the TTL of the value without fetching it, a missing key fails with mmc.ErrNotFound.
+func (c *Client) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	key, err := c.key(key)
+	if err != nil {
+		return err
+	}
+
+	touchMsg := mmc.NewTouch(key, ttl)
+	// The near cache would keep the value past the new TTL if it's shorter
+	defer c.invalidateNear(key)
+	err = c.call(ctx, OpTouch, key, touchMsg)
+	if err != nil {
+		return err
+	}
+	if touchMsg.Error != nil {
+		return touchMsg.Error
+	}
+	return nil
+}
+
+// Delete removes the key, deleting a missing key is not an error.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	key, err := c.key(key)
+	if err != nil {
+		return err
+	}
+	deleteMsg := mmc.NewDelete(key)
+	defer c.invalidateNear(key)
+	err = c.call(ctx, OpDelete, key, deleteMsg)
+	if err != nil {
+		return err
+	}
+	if deleteMsg.Error != nil && !errors.Is(deleteMsg.Error, mmc.ErrNotFound) {
+		return deleteMsg.Error
+	}
+	return nil
+}
+
+// DeleteAsync sends a delete with noreply, returning as soon as the request has been written.
+func (c *Client) DeleteAsync(ctx context.Context, key string) error {
+	key, err := c.key(key)
+	if err != nil {
+		return err
+	}
+	deleteMsg := mmc.NewDelete(key)
+	deleteMsg.NoReply = true
+	defer c.invalidateNear(key)
+	return c.call(ctx, OpDelete, key, deleteMsg)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"memcached-go/mmc"
 	"memcached-go/testutil"
 	"sync"
 	"testing"
@@ -110,3 +111,91 @@ func (s *ClientSuite) testTimeout(cli *Client, i int) {
 		s.Equal(expFlags, flags)
 	}
 }
+
+func (s *ClientSuite) TestCounters() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	_, err = cli.Incr(ctx, "counter", 1)
+	s.ErrorIs(err, mmc.ErrNotFound)
+
+	s.Require().NoError(cli.Add(ctx, "counter", 0, []byte("10"), 0))
+	s.ErrorIs(cli.Add(ctx, "counter", 0, []byte("0"), 0), mmc.ErrNotStored)
+
+	val, err := cli.Incr(ctx, "counter", 5)
+	s.Require().NoError(err)
+	s.Equal(uint64(15), val)
+	val, err = cli.Decr(ctx, "counter", 20)
+	s.Require().NoError(err)
+	s.Equal(uint64(0), val)
+}
+
+func (s *ClientSuite) TestCompareAndSwap() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1, WithNearCache(NearCache{MaxBytes: 1 << 20}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	val, _, cas, err := cli.Gets(ctx, "foo")
+	s.Require().NoError(err)
+	s.Nil(val)
+	s.Zero(cas)
+
+	s.Require().NoError(cli.Set(ctx, "foo", 1, []byte("bar"), 0))
+	val, flags, cas, err := cli.Gets(ctx, "foo")
+	s.Require().NoError(err)
+	s.Equal("bar", string(val))
+	s.Equal(uint16(1), flags)
+
+	s.Require().NoError(cli.CompareAndSwap(ctx, "foo", 2, []byte("baz"), 0, cas))
+	s.ErrorIs(cli.CompareAndSwap(ctx, "foo", 2, []byte("qux"), 0, cas), mmc.ErrExists)
+	// The near cache doesn't keep the swapped value
+	val, flags, err = cli.Get(ctx, "foo")
+	s.Require().NoError(err)
+	s.Equal("baz", string(val))
+	s.Equal(uint16(2), flags)
+
+	s.ErrorIs(cli.CompareAndDelete(ctx, "foo", cas), mmc.ErrExists)
+	_, _, cas, err = cli.Gets(ctx, "foo")
+	s.Require().NoError(err)
+	s.Require().NoError(cli.CompareAndDelete(ctx, "foo", cas))
+	// Like Delete, deleting a missing key is not an error
+	s.NoError(cli.CompareAndDelete(ctx, "foo", cas))
+	s.ErrorIs(cli.CompareAndSwap(ctx, "foo", 0, []byte("bar"), 0, cas), mmc.ErrNotFound)
+}
+
+func (s *ClientSuite) TestExpiration() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	s.Require().NoError(cli.Set(ctx, "foo", 0, []byte("bar"), time.Second))
+	s.Require().NoError(cli.Set(ctx, "expired", 0, []byte("bar"), -time.Second))
+	val, err := cli.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Equal("bar", string(val))
+	val, err = cli.GetV(ctx, "expired")
+	s.Require().NoError(err)
+	s.Nil(val)
+
+	s.ErrorIs(cli.Touch(ctx, "expired", time.Minute), mmc.ErrNotFound)
+	s.Require().NoError(cli.Touch(ctx, "foo", -time.Second))
+	val, err = cli.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Nil(val)
+}
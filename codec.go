@@ -0,0 +1,77 @@
+package memcached_go
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Codec serializes values of a Typed cache. The ID is stored in the flags of every value, so that values written with
+// another codec are detected, IDs up to 15 are reserved for the codecs of this package.
+type Codec[T any] interface {
+	ID() uint8
+	Marshal(v T) ([]byte, error)
+	Unmarshal(data []byte, v *T) error
+}
+
+const (
+	JSONCodecID   uint8 = 1
+	GobCodecID    uint8 = 2
+	BinaryCodecID uint8 = 3
+)
+
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) ID() uint8 {
+	return JSONCodecID
+}
+
+func (JSONCodec[T]) Marshal(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec[T]) Unmarshal(data []byte, v *T) error {
+	return json.Unmarshal(data, v)
+}
+
+// GobCodec encodes every value as a separate stream, including the type description, which is simple but verbose.
+type GobCodec[T any] struct{}
+
+func (GobCodec[T]) ID() uint8 {
+	return GobCodecID
+}
+
+func (GobCodec[T]) Marshal(v T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec[T]) Unmarshal(data []byte, v *T) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// binaryValue is a pointer to a type with binary encoding, e.g. *time.Time.
+type binaryValue[T any] interface {
+	*T
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+// BinaryCodec uses the type's own encoding, e.g. BinaryCodec[time.Time, *time.Time].
+type BinaryCodec[T any, P binaryValue[T]] struct{}
+
+func (BinaryCodec[T, P]) ID() uint8 {
+	return BinaryCodecID
+}
+
+func (BinaryCodec[T, P]) Marshal(v T) ([]byte, error) {
+	return P(&v).MarshalBinary()
+}
+
+func (BinaryCodec[T, P]) Unmarshal(data []byte, v *T) error {
+	return P(v).UnmarshalBinary(data)
+}
@@ -0,0 +1,170 @@
+package memcached_go
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"sync"
+)
+
+// FlagCompressed marks a compressed value by default, see Compression.Flag.
+const FlagCompressed uint16 = 1 << 14
+
+// DefaultCompressionThreshold is the smallest value compressed by default, smaller ones rarely shrink enough.
+const DefaultCompressionThreshold = 1024
+
+// DefaultMaxDecompressedSize bounds decompressed values by default, so that a small corrupt or hostile value can't
+// exhaust memory.
+const DefaultMaxDecompressedSize = 64 << 20
+
+// Compressor compresses values, implementations must be safe for concurrent use.
+type Compressor interface {
+	Compress(src []byte) ([]byte, error)
+	Decompress(src []byte) ([]byte, error)
+}
+
+// Compression configures transparent compression of values.
+type Compression struct {
+	// Compressor is flate with the default level if nil
+	Compressor Compressor
+	// Threshold is the smallest value compressed, DefaultCompressionThreshold if zero
+	Threshold int
+	// Flag is the reserved bit marking compressed values, FlagCompressed if zero. Other clients sharing the data must
+	// use the same bit and format. It must not overlap the bits of other features, nor the low byte used by Typed.
+	Flag uint16
+}
+
+// WithCompression makes Set compress values from the threshold up, and Get decompress them. Values which don't shrink
+// are stored as is. Flags passed to Set must not use the reserved bit.
+func WithCompression(cfg Compression) Option {
+	return func(c *Client) {
+		if cfg.Compressor == nil {
+			cfg.Compressor = NewFlateCompressor(flate.DefaultCompression)
+		}
+		if cfg.Threshold <= 0 {
+			cfg.Threshold = DefaultCompressionThreshold
+		}
+		if cfg.Flag == 0 {
+			cfg.Flag = FlagCompressed
+		}
+		c.compression = &cfg
+		c.reserveFlag("compression", cfg.Flag)
+	}
+}
+
+func (c *Client) compress(val []byte, flags uint16) ([]byte, uint16, error) {
+	if len(val) < c.compression.Threshold {
+		return val, flags, nil
+	}
+	compressed, err := c.compression.Compressor.Compress(val)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(compressed) >= len(val) {
+		return val, flags, nil
+	}
+	return compressed, flags | c.compression.Flag, nil
+}
+
+// FlateCompressor uses raw DEFLATE, writers are pooled as they are expensive to create.
+type FlateCompressor struct {
+	// MaxSize of decompressed values, larger ones fail with ErrTooLarge. DefaultMaxDecompressedSize if zero.
+	MaxSize int
+
+	level   int
+	writers sync.Pool
+}
+
+// NewFlateCompressor creates a compressor with a compress/flate level, invalid levels fall back to the default one.
+func NewFlateCompressor(level int) *FlateCompressor {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		level = flate.DefaultCompression
+	}
+	return &FlateCompressor{level: level}
+}
+
+func (f *FlateCompressor) Compress(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, ok := f.writers.Get().(*flate.Writer)
+	if ok {
+		w.Reset(&buf)
+	} else {
+		w, _ = flate.NewWriter(&buf, f.level)
+	}
+	defer f.writers.Put(w)
+
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (f *FlateCompressor) Decompress(src []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(src))
+	defer r.Close()
+	return readAllLimited(r, f.MaxSize)
+}
+
+// GzipCompressor uses the gzip format, which adds a header and a checksum to DEFLATE, writers are pooled.
+type GzipCompressor struct {
+	// MaxSize of decompressed values, larger ones fail with ErrTooLarge. DefaultMaxDecompressedSize if zero.
+	MaxSize int
+
+	level   int
+	writers sync.Pool
+}
+
+// NewGzipCompressor creates a compressor with a compress/gzip level, invalid levels fall back to the default one.
+func NewGzipCompressor(level int) *GzipCompressor {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+	return &GzipCompressor{level: level}
+}
+
+func (g *GzipCompressor) Compress(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, ok := g.writers.Get().(*gzip.Writer)
+	if ok {
+		w.Reset(&buf)
+	} else {
+		w, _ = gzip.NewWriterLevel(&buf, g.level)
+	}
+	defer g.writers.Put(w)
+
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (g *GzipCompressor) Decompress(src []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return readAllLimited(r, g.MaxSize)
+}
+
+// readAllLimited reads up to maxSize bytes, DefaultMaxDecompressedSize if not positive, and fails if there are more.
+func readAllLimited(r io.Reader, maxSize int) ([]byte, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxDecompressedSize
+	}
+	out, err := io.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > maxSize {
+		return nil, ErrTooLarge
+	}
+	return out, nil
+}
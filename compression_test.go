@@ -0,0 +1,184 @@
+package memcached_go
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"math/rand/v2"
+	"memcached-go/testutil"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CompressionSuite struct {
+	testutil.BaseSuite
+}
+
+func TestCompressionSuite(t *testing.T) {
+	suite.Run(t, new(CompressionSuite))
+}
+
+func (s *CompressionSuite) TestCompressors() {
+	val := bytes.Repeat([]byte("compressible "), 1000)
+	for _, compressor := range []Compressor{NewFlateCompressor(flate.BestSpeed), NewGzipCompressor(100)} {
+		for i := 0; i < 2; i++ {
+			compressed, err := compressor.Compress(val)
+			s.Require().NoError(err)
+			s.Less(len(compressed), len(val)/10)
+
+			decompressed, err := compressor.Decompress(compressed)
+			s.Require().NoError(err)
+			s.Equal(val, decompressed)
+		}
+	}
+}
+
+func (s *CompressionSuite) TestCompression() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1, WithCompression(Compression{}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	val := bytes.Repeat([]byte("compressible "), 1000)
+	s.Require().NoError(cli.Set(ctx, "large", 3, val, 0))
+	stored, flags, _ := fake.Get("large")
+	s.Less(len(stored), len(val))
+	s.Equal(uint32(3|FlagCompressed), flags)
+
+	read, readFlags, err := cli.Get(ctx, "large")
+	s.Require().NoError(err)
+	s.Equal(val, read)
+	s.Equal(uint16(3), readFlags)
+
+	// Below the threshold
+	s.Require().NoError(cli.Set(ctx, "small", 3, []byte("small"), 0))
+	_, flags, _ = fake.Get("small")
+	s.Equal(uint32(3), flags)
+
+	// Doesn't shrink
+	random := make([]byte, 4096)
+	for i := range random {
+		random[i] = byte(rand.Uint32())
+	}
+	s.Require().NoError(cli.Set(ctx, "random", 0, random, 0))
+	stored, flags, _ = fake.Get("random")
+	s.Equal(random, stored)
+	s.Equal(uint32(0), flags)
+	read, err = cli.GetV(ctx, "random")
+	s.Require().NoError(err)
+	s.Equal(random, read)
+
+	s.ErrorIs(cli.Set(ctx, "small", FlagCompressed, []byte("small"), 0), ErrReservedFlags)
+	s.ErrorIs(cli.SetAsync(ctx, "small", FlagCompressed, []byte("small"), 0), ErrReservedFlags)
+	p := cli.Pipeline()
+	p.Set("small", FlagCompressed, []byte("small"), 0)
+	_, err = p.Exec(ctx)
+	s.ErrorIs(err, ErrReservedFlags)
+	// Neither was stored
+	read, err = cli.GetV(ctx, "small")
+	s.Require().NoError(err)
+	s.Equal("small", string(read))
+}
+
+func (s *CompressionSuite) TestInterop() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	// Another client marking compressed values with bit 8
+	compressor := NewFlateCompressor(flate.DefaultCompression)
+	cli, err := NewClient(fake.Addr(), 1, 1, WithCompression(Compression{Compressor: compressor, Flag: 1 << 8}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	val := bytes.Repeat([]byte("compressible "), 1000)
+	compressed, err := compressor.Compress(val)
+	s.Require().NoError(err)
+	fake.Put("foreign", 1<<8|1, compressed)
+
+	read, flags, err := cli.Get(ctx, "foreign")
+	s.Require().NoError(err)
+	s.Equal(val, read)
+	s.Equal(uint16(1), flags)
+
+	// Bit 14 is free to use then
+	s.Require().NoError(cli.Set(ctx, "own", FlagCompressed, []byte("small"), 0))
+}
+
+func (s *CompressionSuite) TestWithChunking() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1,
+		WithCompression(Compression{}), WithChunking(Chunking{ChunkSize: 1000}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	// Compressed first, and chunked only if still too large
+	ctx := context.Background()
+	random := make([]byte, 2500)
+	rnd := rand.New(rand.NewPCG(1, 2))
+	for i := range random {
+		random[i] = 'a' + byte(rnd.Uint32()%16)
+	}
+	s.Require().NoError(cli.Set(ctx, "large", 1, random, 0))
+	_, flags, _ := fake.Get("large")
+	s.Equal(uint32(1|FlagCompressed|FlagChunked), flags)
+
+	read, readFlags, err := cli.Get(ctx, "large")
+	s.Require().NoError(err)
+	s.Equal(random, read)
+	s.Equal(uint16(1), readFlags)
+}
+
+func (s *CompressionSuite) TestFlagConflicts() {
+	_, err := NewClient("localhost:0", 0, 1, WithCompression(Compression{Flag: 1 << 3}))
+	s.ErrorIs(err, ErrFlagConflict)
+
+	_, err = NewClient("localhost:0", 0, 1, WithCompression(Compression{}), WithChunking(Chunking{Flag: FlagCompressed}))
+	s.ErrorIs(err, ErrFlagConflict)
+}
+
+func (s *CompressionSuite) TestMaxDecompressedSize() {
+	val := bytes.Repeat([]byte("compressible "), 1000)
+	flateCompressor := NewFlateCompressor(flate.DefaultCompression)
+	gzipCompressor := NewGzipCompressor(flate.DefaultCompression)
+	for _, compressor := range []Compressor{flateCompressor, gzipCompressor} {
+		compressed, err := compressor.Compress(val)
+		s.Require().NoError(err)
+
+		flateCompressor.MaxSize, gzipCompressor.MaxSize = len(val)-1, len(val)-1
+		_, err = compressor.Decompress(compressed)
+		s.ErrorIs(err, ErrTooLarge)
+
+		flateCompressor.MaxSize, gzipCompressor.MaxSize = len(val), len(val)
+		_, err = compressor.Decompress(compressed)
+		s.NoError(err)
+	}
+}
+
+func (s *CompressionSuite) TestGetMulti() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1, WithCompression(Compression{}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	val := bytes.Repeat([]byte("compressible "), 1000)
+	s.Require().NoError(cli.Set(ctx, "large", 3, val, 0))
+
+	items, err := cli.GetMulti(ctx, []string{"large"})
+	s.Require().NoError(err)
+	s.Equal(val, items["large"].Value)
+	s.Equal(uint16(3), items["large"].Flags)
+}
@@ -0,0 +1,13 @@
+package memcached_go
+
+import "errors"
+
+var (
+	ErrReservedFlags = errors.New("flags use bits reserved by the client")
+	ErrFlagConflict  = errors.New("flag bit used by more than one feature")
+	ErrTooLarge      = errors.New("value too large")
+	ErrChecksum      = errors.New("checksum mismatch")
+	ErrCodecMismatch = errors.New("value written with another codec")
+	// ErrNotFound is returned by loaders when there is no value to cache
+	ErrNotFound = errors.New("not found")
+)
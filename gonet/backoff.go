@@ -0,0 +1,66 @@
+package gonet
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff decides how long to wait before the next attempt of a failing operation (reconnect, retry).
+// Implementations must be stateless and safe for concurrent use, the caller tracks attempts and resets them on success.
+type Backoff interface {
+	// Next returns the delay before the given attempt (starting at 1), prev is the delay returned for the previous
+	// attempt, or 0 for the first one.
+	Next(attempt int, prev time.Duration) time.Duration
+}
+
+// ConstantBackoff always waits the same Delay.
+type ConstantBackoff struct {
+	Delay time.Duration
+}
+
+func (b ConstantBackoff) Next(_ int, _ time.Duration) time.Duration {
+	return b.Delay
+}
+
+// ExponentialBackoff doubles the delay on every attempt, starting with Base and capped at Max.
+type ExponentialBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+func (b ExponentialBackoff) Next(attempt int, _ time.Duration) time.Duration {
+	delay := b.Base
+	for i := 1; i < attempt && delay < b.Max; i++ {
+		delay *= 2
+	}
+	return min(delay, b.Max)
+}
+
+// DecorrelatedJitterBackoff picks a random delay between Base and 3x the previous delay, capped at Max.
+// See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+type DecorrelatedJitterBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+func (b DecorrelatedJitterBackoff) Next(_ int, prev time.Duration) time.Duration {
+	upper := max(prev*3, b.Base)
+	if upper == b.Base {
+		return min(b.Base, b.Max)
+	}
+	return min(b.Base+rand.N(upper-b.Base), b.Max)
+}
+
+// DefaultBackoff is used for reconnects, unless overridden with WithBackoff.
+var DefaultBackoff Backoff = DecorrelatedJitterBackoff{Base: 10 * time.Millisecond, Max: 5 * time.Second}
+
+// Clock abstracts waiting, so the reconnect schedule can be driven by a fake clock in tests.
+type Clock interface {
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,49 @@
+package gonet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type BackoffSuite struct {
+	BaseSuite
+}
+
+func TestBackoffSuite(t *testing.T) {
+	suite.Run(t, new(BackoffSuite))
+}
+
+func (s *BackoffSuite) TestConstant() {
+	b := ConstantBackoff{Delay: 7 * time.Millisecond}
+	var delay time.Duration
+	for attempt := 1; attempt <= 5; attempt++ {
+		delay = b.Next(attempt, delay)
+		s.Equal(7*time.Millisecond, delay)
+	}
+}
+
+func (s *BackoffSuite) TestExponential() {
+	b := ExponentialBackoff{Base: 10 * time.Millisecond, Max: 100 * time.Millisecond}
+	expected := []time.Duration{10, 20, 40, 80, 100, 100}
+	var delay time.Duration
+	for i, exp := range expected {
+		delay = b.Next(i+1, delay)
+		s.Equal(exp*time.Millisecond, delay)
+	}
+}
+
+func (s *BackoffSuite) TestDecorrelatedJitter() {
+	b := DecorrelatedJitterBackoff{Base: 10 * time.Millisecond, Max: time.Second}
+
+	delay := b.Next(1, 0)
+	s.Equal(10*time.Millisecond, delay)
+
+	for attempt := 2; attempt <= 100; attempt++ {
+		prev := delay
+		delay = b.Next(attempt, prev)
+		s.GreaterOrEqual(delay, b.Base)
+		s.LessOrEqual(delay, min(3*prev, b.Max))
+	}
+}
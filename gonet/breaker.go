@@ -0,0 +1,234 @@
+package gonet
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type BreakerState int
+
+const (
+	// BreakerClosed lets all requests through, while tracking their outcomes
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails all requests fast with ErrCircuitOpen
+	BreakerOpen
+	// BreakerHalfOpen lets a limited number of probe requests through, to find out if the server has recovered
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+const breakerBuckets = 10
+
+type BreakerConfig struct {
+	// Window over which error and timeout rates are computed, DefaultBreakerConfig.Window if not set
+	Window time.Duration
+	// MinRequests in the window before the breaker may trip, so that a couple of errors don't open it
+	MinRequests int
+	// ErrorRate of connection errors (see IsConnectionError) which trips the breaker, 0 disables it
+	ErrorRate float64
+	// TimeoutRate of requests exceeding the context deadline which trips the breaker, 0 disables it
+	TimeoutRate float64
+	// OpenTimeout is how long the breaker stays open before letting probes through
+	OpenTimeout time.Duration
+	// HalfOpenProbes have to succeed to close the breaker again, a single failed probe opens it.
+	// DefaultBreakerConfig.HalfOpenProbes if not set.
+	HalfOpenProbes int
+}
+
+// DefaultBreakerConfig opens the breaker when half of the requests fail or time out in the last 10 seconds.
+var DefaultBreakerConfig = BreakerConfig{
+	Window:         10 * time.Second,
+	MinRequests:    20,
+	ErrorRate:      0.5,
+	TimeoutRate:    0.5,
+	OpenTimeout:    5 * time.Second,
+	HalfOpenProbes: 3,
+}
+
+// Breaker is a circuit breaker, protecting callers from waiting on a sick server, e.g. one accepting connections but
+// answering slowly. See WithBreaker.
+type Breaker struct {
+	cfg BreakerConfig
+	now func() time.Time
+
+	lock     sync.Mutex
+	state    BreakerState
+	openedAt time.Time
+	// Probes let through, and probes which succeeded in the half-open state
+	probes    int
+	succeeded int
+	buckets   [breakerBuckets]breakerBucket
+}
+
+type breakerBucket struct {
+	start    time.Time
+	total    int
+	errors   int
+	timeouts int
+}
+
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	// The window is split into buckets, each has to be at least a nanosecond long
+	if cfg.Window < breakerBuckets {
+		cfg.Window = DefaultBreakerConfig.Window
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = DefaultBreakerConfig.HalfOpenProbes
+	}
+	return &Breaker{cfg: cfg, now: time.Now}
+}
+
+// WithBreaker puts the circuit breaker in front of all calls of the client.
+func WithBreaker(b *Breaker) ClientOption {
+	return func(c *Client) {
+		c.breaker = b
+	}
+}
+
+func (b *Breaker) State() BreakerState {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.maybeHalfOpen(b.now())
+	return b.state
+}
+
+// allow returns ErrCircuitOpen if the request must not be sent, otherwise the outcome of the request must be reported
+// with the returned function.
+func (b *Breaker) allow() (func(err error), error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.maybeHalfOpen(b.now())
+	switch b.state {
+	case BreakerOpen:
+		return nil, ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.probes >= b.cfg.HalfOpenProbes {
+			return nil, ErrCircuitOpen
+		}
+		b.probes++
+		return b.probeDone, nil
+	}
+	return b.done, nil
+}
+
+func (b *Breaker) maybeHalfOpen(now time.Time) {
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.state = BreakerHalfOpen
+		b.probes = 0
+		b.succeeded = 0
+	}
+}
+
+func (b *Breaker) done(err error) {
+	isError, isTimeout, ok := classify(err)
+	if !ok {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.state != BreakerClosed {
+		// Completed after the breaker has tripped, the outcome is outdated
+		return
+	}
+
+	now := b.now()
+	bucket := b.bucket(now)
+	bucket.total++
+	if isError {
+		bucket.errors++
+	}
+	if isTimeout {
+		bucket.timeouts++
+	}
+
+	var total, errs, timeouts int
+	for i := range b.buckets {
+		if now.Sub(b.buckets[i].start) < b.cfg.Window {
+			total += b.buckets[i].total
+			errs += b.buckets[i].errors
+			timeouts += b.buckets[i].timeouts
+		}
+	}
+	if total < b.cfg.MinRequests {
+		return
+	}
+	if exceeds(errs, total, b.cfg.ErrorRate) || exceeds(timeouts, total, b.cfg.TimeoutRate) {
+		b.open(now)
+	}
+}
+
+func (b *Breaker) probeDone(err error) {
+	isError, isTimeout, ok := classify(err)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.state != BreakerHalfOpen {
+		return
+	}
+	if !ok {
+		// Canceled by the caller, let another probe through instead
+		b.probes--
+		return
+	}
+	if isError || isTimeout {
+		b.open(b.now())
+		return
+	}
+	b.succeeded++
+	if b.succeeded >= b.cfg.HalfOpenProbes {
+		b.state = BreakerClosed
+		b.buckets = [breakerBuckets]breakerBucket{}
+	}
+}
+
+func (b *Breaker) open(now time.Time) {
+	b.state = BreakerOpen
+	b.openedAt = now
+}
+
+// bucket returns the bucket for the current time, resetting it if it belongs to an older window.
+func (b *Breaker) bucket(now time.Time) *breakerBucket {
+	width := b.cfg.Window / breakerBuckets
+	start := now.Truncate(width)
+	bucket := &b.buckets[(start.UnixNano()/int64(width))%breakerBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = breakerBucket{start: start}
+	}
+	return bucket
+}
+
+// classify reports whether the outcome is an error or a timeout, ok is false for outcomes which say nothing about the
+// server's health.
+func classify(err error) (isError, isTimeout, ok bool) {
+	switch {
+	case err == nil:
+		return false, false, true
+	case errors.Is(err, context.DeadlineExceeded):
+		return false, true, true
+	case IsConnectionError(err):
+		return true, false, true
+	}
+	return false, false, false
+}
+
+func exceeds(n, total int, rate float64) bool {
+	return rate > 0 && float64(n) >= rate*float64(total)
+}
@@ -0,0 +1,160 @@
+package gonet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type BreakerSuite struct {
+	BaseSuite
+
+	now time.Time
+}
+
+func TestBreakerSuite(t *testing.T) {
+	suite.Run(t, new(BreakerSuite))
+}
+
+func (s *BreakerSuite) newBreaker() *Breaker {
+	s.now = time.Unix(1_000_000, 0)
+	b := NewBreaker(BreakerConfig{
+		Window:         10 * time.Second,
+		MinRequests:    4,
+		ErrorRate:      0.5,
+		TimeoutRate:    0.5,
+		OpenTimeout:    5 * time.Second,
+		HalfOpenProbes: 2,
+	})
+	b.now = func() time.Time { return s.now }
+	return b
+}
+
+func (s *BreakerSuite) record(b *Breaker, err error) {
+	done, allowErr := b.allow()
+	s.Require().NoError(allowErr)
+	done(err)
+}
+
+func (s *BreakerSuite) TestTripsOnErrors() {
+	b := s.newBreaker()
+	connErr := fmt.Errorf("%w: boom", ErrReceive)
+
+	// Not enough requests to trip yet
+	s.record(b, connErr)
+	s.record(b, connErr)
+	s.Equal(BreakerClosed, b.State())
+
+	s.record(b, nil)
+	s.record(b, connErr)
+	s.Equal(BreakerOpen, b.State())
+
+	_, err := b.allow()
+	s.ErrorIs(err, ErrCircuitOpen)
+}
+
+func (s *BreakerSuite) TestTripsOnTimeouts() {
+	b := s.newBreaker()
+	for i := 0; i < 4; i++ {
+		s.record(b, context.DeadlineExceeded)
+	}
+	s.Equal(BreakerOpen, b.State())
+}
+
+func (s *BreakerSuite) TestIgnoresOtherErrors() {
+	b := s.newBreaker()
+	for i := 0; i < 10; i++ {
+		s.record(b, context.Canceled)
+		s.record(b, errors.New("protocol error"))
+	}
+	s.Equal(BreakerClosed, b.State())
+}
+
+func (s *BreakerSuite) TestSlidingWindow() {
+	b := s.newBreaker()
+	connErr := fmt.Errorf("%w: boom", ErrSend)
+
+	s.record(b, connErr)
+	s.record(b, connErr)
+	s.record(b, connErr)
+
+	// Old errors fall out of the window
+	s.now = s.now.Add(11 * time.Second)
+	s.record(b, connErr)
+	s.record(b, nil)
+	s.record(b, nil)
+	s.record(b, nil)
+	s.Equal(BreakerClosed, b.State())
+}
+
+func (s *BreakerSuite) TestHalfOpen() {
+	b := s.newBreaker()
+	for i := 0; i < 4; i++ {
+		s.record(b, ErrConnClosed)
+	}
+	s.Equal(BreakerOpen, b.State())
+
+	s.now = s.now.Add(5 * time.Second)
+	s.Equal(BreakerHalfOpen, b.State())
+
+	// Only the configured number of probes is let through
+	done1, err := b.allow()
+	s.Require().NoError(err)
+	done2, err := b.allow()
+	s.Require().NoError(err)
+	_, err = b.allow()
+	s.ErrorIs(err, ErrCircuitOpen)
+
+	// A failed probe opens the breaker again
+	done1(ErrConnClosed)
+	s.Equal(BreakerOpen, b.State())
+	done2(nil)
+	s.Equal(BreakerOpen, b.State())
+
+	s.now = s.now.Add(5 * time.Second)
+	s.record(b, nil)
+	s.Equal(BreakerHalfOpen, b.State())
+	s.record(b, nil)
+	s.Equal(BreakerClosed, b.State())
+}
+
+func (s *BreakerSuite) TestZeroConfig() {
+	b := NewBreaker(BreakerConfig{ErrorRate: 0.5, OpenTimeout: time.Minute})
+	s.Equal(DefaultBreakerConfig.Window, b.cfg.Window)
+	s.Equal(DefaultBreakerConfig.HalfOpenProbes, b.cfg.HalfOpenProbes)
+
+	// Doesn't panic on a zero bucket width
+	s.record(b, ErrConnClosed)
+	s.Equal(BreakerOpen, b.State())
+}
+
+func (s *BreakerSuite) TestClientWithBreaker() {
+	h := &SlowOnceRequestHandler{delay: time.Second}
+	th := WithTracking(NewServerFactory(h))
+
+	l := s.SetupListener(th)
+
+	cfg := DefaultBreakerConfig
+	cfg.MinRequests = 1
+	cli, err := NewClient(l.Address().String(), 1, 1, WithBreaker(NewBreaker(cfg)))
+	s.Require().NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = cli.Call(ctx, &TestMessage{inText: "slow"})
+	s.ErrorIs(err, context.DeadlineExceeded)
+
+	// Fails fast, without waiting for the deadline
+	start := time.Now()
+	err = cli.Call(context.Background(), &TestMessage{inText: "fast"})
+	s.ErrorIs(err, ErrCircuitOpen)
+	s.Less(time.Since(start), 10*time.Millisecond)
+
+	cli.Close()
+	s.Require().NoError(l.Close())
+	th.Wait()
+}
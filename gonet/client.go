@@ -2,7 +2,6 @@ package gonet
 
 import (
 	"context"
-	"math/rand"
 	"slices"
 	"sync"
 	"sync/atomic"
@@ -14,28 +13,65 @@ type Client struct {
 	minCons int
 	maxCons int
 
+	backoff Backoff
+	clock   Clock
+	breaker *Breaker
+	dial    func(addr string) (*Connection, error)
+
 	isOpen atomic.Bool
 
 	slots chan *Connection
 
 	conns    []*Connection
 	connLock sync.Mutex
+
+	// Wakes up the reconnect loop, buffered so that a pending request is never lost
+	grow chan struct{}
+	done chan struct{}
 }
 
-func NewClient(addr string, minCons, maxCons int) (*Client, error) {
+// ClientOption customizes the Client created with NewClient.
+type ClientOption func(c *Client)
+
+// WithBackoff sets the policy for delaying reconnects after failed dials.
+func WithBackoff(b Backoff) ClientOption {
+	return func(c *Client) {
+		c.backoff = b
+	}
+}
+
+// WithClock overrides the clock used to wait between reconnects, meant for tests.
+func WithClock(clock Clock) ClientOption {
+	return func(c *Client) {
+		c.clock = clock
+	}
+}
+
+func NewClient(addr string, minCons, maxCons int, opts ...ClientOption) (*Client, error) {
 	c := &Client{
 		addr:    addr,
 		minCons: minCons,
 		maxCons: maxCons,
 
+		backoff: DefaultBackoff,
+		clock:   realClock{},
+		dial:    NewConnection,
+
 		slots: make(chan *Connection, maxCons),
 		conns: make([]*Connection, 0, maxCons),
+
+		grow: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
 	err := c.connect(minCons)
 	if err != nil {
 		return nil, err
 	}
 	c.isOpen.Store(true)
+	go c.reconnectLoop()
 	return c, nil
 }
 
@@ -44,7 +80,7 @@ func (c *Client) connect(n int) error {
 	defer c.connLock.Unlock()
 
 	for i := 0; i < n; i++ {
-		conn, err := NewConnection(c.addr)
+		conn, err := c.dial(c.addr)
 		if err != nil {
 			return err
 		}
@@ -55,9 +91,41 @@ func (c *Client) connect(n int) error {
 }
 
 func (c *Client) Call(ctx context.Context, msg Message) error {
+	if c.breaker == nil {
+		return c.call(ctx, msg)
+	}
+
+	done, err := c.breaker.allow()
+	if err != nil {
+		return err
+	}
+	err = c.call(ctx, msg)
+	done(err)
+	return err
+}
+
+func (c *Client) call(ctx context.Context, msg Message) error {
+	conn, req, err := c.send(ctx, msg)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-req.completed:
+		return req.err
+	case <-ctx.Done():
+		if am, ok := msg.(AbortableMessage); ok && am.AbortOnCancel() {
+			conn.abort()
+		}
+		return ctx.Err()
+	}
+}
+
+// send queues the message on a pooled connection, waiting for one if needed, and returns the connection used.
+func (c *Client) send(ctx context.Context, msg Message) (*Connection, *PendingMessage, error) {
 	for {
 		if len(c.slots) == 0 {
-			go c.maybeGrow(0)
+			c.requestGrow()
 		}
 
 		select {
@@ -73,68 +141,85 @@ func (c *Client) Call(ctx context.Context, msg Message) error {
 			c.slots <- conn
 
 			if err != nil {
-				return err
-			}
-
-			select {
-			case <-req.completed:
-				return req.err
-			case <-ctx.Done():
-				return ctx.Err()
+				return nil, nil, err
 			}
+			return conn, req, nil
 
 		case <-ctx.Done():
-			return ctx.Err()
+			return nil, nil, ctx.Err()
 		}
 	}
 }
 
-func (c *Client) maybeGrow(initialWait time.Duration) {
-	if !c.isOpen.Load() {
-		return
+// requestGrow asks the reconnect loop for one more connection, without blocking.
+func (c *Client) requestGrow() {
+	select {
+	case c.grow <- struct{}{}:
+	default:
+		// Already requested
 	}
+}
 
-	if !c.connLock.TryLock() {
-		// Already being handled
-		return
-	}
-	defer c.connLock.Unlock()
+// reconnectLoop is the only goroutine opening connections after the client has been created. Failed dials are retried
+// according to the backoff policy, which is reset after every successful dial.
+func (c *Client) reconnectLoop() {
+	attempt := 0
+	var delay time.Duration
+	for {
+		select {
+		case <-c.grow:
+		case <-c.done:
+			return
+		}
 
-	// Waiting while holding the lock, as we want to prevent other goroutines from spamming reconnections
-	if initialWait > 0 {
-		time.Sleep(initialWait)
+		for !c.maybeGrow() {
+			attempt++
+			delay = c.backoff.Next(attempt, delay)
+			select {
+			case <-c.clock.After(delay):
+			case <-c.done:
+				return
+			}
+		}
+		attempt = 0
+		delay = 0
 	}
+}
+
+// maybeGrow adds a connection if the pool is not full, returns false only if dialing failed.
+func (c *Client) maybeGrow() bool {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
 
 	if !c.isOpen.Load() {
-		return
+		return true
 	}
 
 	// Remove all dead connections first
 	c.conns = slices.DeleteFunc(c.conns, func(conn *Connection) bool { return !conn.IsOpen() })
 
-	if len(c.conns) < c.maxCons {
-		conn, err := NewConnection(c.addr)
+	// The request might be stale, don't grow if there are idle connections by now
+	if len(c.conns) < c.maxCons && len(c.slots) == 0 {
+		conn, err := c.dial(c.addr)
 		if err != nil {
-			go c.maybeGrow(nextDelay(initialWait))
-			return
+			return false
 		}
 		c.conns = append(c.conns, conn)
 		c.slots <- conn
 	}
+	return true
 }
 
 func (c *Client) Close() {
 	c.connLock.Lock()
 	defer c.connLock.Unlock()
 
-	c.isOpen.Store(false)
+	if !c.isOpen.CompareAndSwap(true, false) {
+		return
+	}
+	close(c.done)
 	for _, conn := range c.conns {
 		conn.Close()
 	}
 	c.conns = nil
 }
-
-func nextDelay(delay time.Duration) time.Duration {
-	maxDelay := 5 * time.Second
-	return min(delay+10*time.Millisecond+time.Duration(rand.Float32()*float32(delay)), maxDelay)
-}
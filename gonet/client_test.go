@@ -2,8 +2,10 @@ package gonet
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -104,7 +106,8 @@ func (s *ClientSuite) TestClientMaxConnections() {
 
 	s.Equal(0, numConnections(cli))
 
-	s.testClients(cli, workers, iterations)
+	// Slow writes keep connections out of the pool, so that callers have to wait and the pool grows to the max
+	s.testClientsWithDelay(cli, workers, iterations, time.Millisecond)
 
 	s.Equal(3, numConnections(cli))
 
@@ -138,13 +141,17 @@ func (s *ClientSuite) TestClientMaxConnections() {
 //}
 
 func (s *ClientSuite) testClients(cli *Client, workers int, iterations int) {
+	s.testClientsWithDelay(cli, workers, iterations, 0)
+}
+
+func (s *ClientSuite) testClientsWithDelay(cli *Client, workers int, iterations int, writeDelay time.Duration) {
 	wg := &sync.WaitGroup{}
 	wg.Add(workers)
 
 	clientTest := func(worker int) {
 		for i := 1; i <= iterations; i++ {
 			text := fmt.Sprintf("hello %d from worker %d", i, worker)
-			msg := &TestMessage{inText: text}
+			msg := &TestMessage{inText: text, writeDelay: writeDelay}
 			sendTime := s.NowUnixMicro()
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
@@ -198,3 +205,69 @@ func numConnections(cli *Client) int {
 
 	return len(cli.conns)
 }
+
+type fakeClock struct {
+	waits chan time.Duration
+	fire  chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{waits: make(chan time.Duration, 100), fire: make(chan time.Time)}
+}
+
+func (f *fakeClock) After(d time.Duration) <-chan time.Time {
+	f.waits <- d
+	return f.fire
+}
+
+func (s *ClientSuite) TestClientReconnectBackoff() {
+	h := &TestRequestHandler{}
+	th := WithTracking(NewServerFactory(h))
+
+	l := s.SetupListener(th)
+
+	clock := newFakeClock()
+	var failDials atomic.Int32
+	failDials.Store(3)
+	dial := func(c *Client) {
+		c.dial = func(addr string) (*Connection, error) {
+			if failDials.Add(-1) >= 0 {
+				return nil, errors.New("dial failed")
+			}
+			return NewConnection(addr)
+		}
+	}
+	backoff := ExponentialBackoff{Base: time.Second, Max: time.Minute}
+	cli, err := NewClient(l.Address().String(), 0, 2, WithBackoff(backoff), WithClock(clock), dial)
+	s.Require().NoError(err)
+
+	called := make(chan error)
+	go func() {
+		called <- cli.Call(context.Background(), &TestMessage{inText: "hello"})
+	}()
+
+	// Three failed dials, each followed by a wait according to the backoff policy
+	s.Equal(time.Second, <-clock.waits)
+	clock.fire <- time.Now()
+	s.Equal(2*time.Second, <-clock.waits)
+	clock.fire <- time.Now()
+	s.Equal(4*time.Second, <-clock.waits)
+	clock.fire <- time.Now()
+
+	s.Require().NoError(<-called)
+	s.Equal(1, numConnections(cli))
+
+	// The backoff is reset after a successful dial, the only connection is taken to make the next call grow the pool
+	failDials.Store(1)
+	conn := <-cli.slots
+	go func() {
+		called <- cli.Call(context.Background(), &TestMessage{inText: "hello again"})
+	}()
+	s.Equal(time.Second, <-clock.waits)
+	cli.slots <- conn
+	s.Require().NoError(<-called)
+
+	cli.Close()
+	s.Require().NoError(l.Close())
+	th.Wait()
+}
@@ -3,11 +3,19 @@ package gonet
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"runtime"
 	"sync/atomic"
 )
 
+const (
+	writeBufferSize = 64 * 1024
+	// Flush once this many bytes are buffered, even if more requests are ready
+	flushThreshold = 32 * 1024
+)
+
 type Connection struct {
 	conn   net.Conn
 	isOpen atomic.Bool
@@ -21,7 +29,10 @@ func NewConnection(server string) (*Connection, error) {
 	if err != nil {
 		return nil, err
 	}
+	return newConnection(conn), nil
+}
 
+func newConnection(conn net.Conn) *Connection {
 	c := &Connection{
 		conn: conn,
 
@@ -31,7 +42,7 @@ func NewConnection(server string) (*Connection, error) {
 	c.isOpen.Store(true)
 	go c.requestLoop()
 	go c.responseLoop()
-	return c, nil
+	return c
 }
 
 func (c *Connection) IsOpen() bool {
@@ -42,6 +53,11 @@ func (c *Connection) Send(ctx context.Context, msg Message) (*PendingMessage, er
 	if !c.IsOpen() {
 		return nil, ErrConnClosed
 	}
+	if vm, ok := msg.(ValidatedMessage); ok {
+		if err := vm.Validate(); err != nil {
+			return nil, err
+		}
+	}
 
 	req := &PendingMessage{msg: msg, completed: make(chan struct{})}
 	select {
@@ -75,10 +91,17 @@ func (c *Connection) Close() {
 	close(c.requests)
 }
 
+// abort closes the network connection while requests may be in flight, they fail once the loops run into it.
+func (c *Connection) abort() {
+	c.isOpen.Store(false)
+	_ = c.conn.Close()
+}
+
 func (c *Connection) requestLoop() {
 	defer close(c.pending)
 
-	w := bufio.NewWriter(c.conn)
+	w := bufio.NewWriterSize(c.conn, writeBufferSize)
+	var batch []*PendingMessage
 	for req := range c.requests {
 		if w == nil {
 			req.err = ErrConnClosed
@@ -86,7 +109,17 @@ func (c *Connection) requestLoop() {
 			continue
 		}
 
-		err := req.msg.WriteRequest(w)
+		// Coalesce all requests which are ready into a single flush, to save syscalls
+		batch = append(batch[:0], req)
+		err := c.writeRequest(w, req)
+		for err == nil && w.Buffered() < flushThreshold {
+			next, ok := c.nextReady()
+			if !ok {
+				break
+			}
+			batch = append(batch, next)
+			err = c.writeRequest(w, next)
+		}
 		if err == nil {
 			err = w.Flush()
 		}
@@ -95,13 +128,51 @@ func (c *Connection) requestLoop() {
 			// stop writing
 			w = nil
 			c.isOpen.Store(false)
-			req.err = fmt.Errorf("sending error: %w", err)
-			close(req.completed)
+			for _, failed := range batch {
+				failed.err = fmt.Errorf("%w: %w", ErrSend, err)
+				close(failed.completed)
+			}
 			continue
 		}
 
-		c.pending <- req
+		for _, sent := range batch {
+			if nr, ok := sent.msg.(NoReplyMessage); ok && nr.SkipResponse() {
+				sent.noReply = true
+				close(sent.completed)
+			}
+			c.pending <- sent
+		}
+	}
+}
+
+// nextReady returns the next request only if it's available without waiting. Once the queue is empty, other
+// goroutines get a chance to queue their requests, which are then written with the same flush.
+func (c *Connection) nextReady() (*PendingMessage, bool) {
+	for yielded := false; ; yielded = true {
+		select {
+		case req, ok := <-c.requests:
+			return req, ok
+		default:
+		}
+		if yielded {
+			return nil, false
+		}
+		runtime.Gosched()
+	}
+}
+
+func (c *Connection) writeRequest(w *bufio.Writer, req *PendingMessage) error {
+	if bm, ok := req.msg.(BuffersMessage); ok {
+		if bufs, ok := bm.RequestBuffers(nil); ok {
+			// Keep the order of requests, anything buffered so far must be written first
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			_, err := bufs.WriteTo(c.conn)
+			return err
+		}
 	}
+	return req.msg.WriteRequest(w)
 }
 
 func (c *Connection) responseLoop() {
@@ -110,24 +181,31 @@ func (c *Connection) responseLoop() {
 	r := bufio.NewReader(c.conn)
 	for resp := range c.pending {
 		if r == nil {
-			resp.err = ErrConnClosed
-			close(resp.completed)
+			if !resp.noReply {
+				resp.err = ErrConnClosed
+				close(resp.completed)
+			}
 			continue
 		}
 
 		err := resp.msg.ReadResponse(r)
 		if err != nil {
-			resp.err = fmt.Errorf("receiving error: %w", err)
 			// stop reading
 			r = nil
 			c.isOpen.Store(false)
 		}
+		if resp.noReply {
+			continue
+		}
+		if err != nil {
+			resp.err = fmt.Errorf("%w: %w", ErrReceive, err)
+		}
 		close(resp.completed)
 	}
 }
 
 func (c *Connection) closeConnection() {
-	if err := c.conn.Close(); err != nil {
+	if err := c.conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
 		// todo: log on error instead of panicking
 		panic(err)
 	}
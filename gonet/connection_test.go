@@ -5,9 +5,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"memcached-go/testutil"
+	"net"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -26,9 +29,13 @@ type TestMessage struct {
 	inText  string
 	outText string
 	outTS   time.Time
+
+	// Keeps the connection busy writing, to simulate contention
+	writeDelay time.Duration
 }
 
 func (t *TestMessage) WriteRequest(w *bufio.Writer) error {
+	time.Sleep(t.writeDelay)
 	realInText := strings.Replace(t.inText, "\n", "-", -1)
 	_, err := w.WriteString(realInText + "\n")
 	return err
@@ -116,6 +123,7 @@ func (s *ConnectionSuite) TestConnectionClose() {
 	err = conn.Call(context.Background(), &TestMessage{inText: "Expected to fail gracefully"})
 	s.Require().Error(err)
 	s.Assert().ErrorContains(err, "receiving error")
+	s.Assert().True(IsConnectionError(err))
 	err = conn.Call(context.Background(), &TestMessage{inText: "Expected to fail gracefully too"})
 	s.Require().Error(err)
 	if !errors.Is(err, ErrConnClosed) {
@@ -155,3 +163,70 @@ func (s *ConnectionSuite) testConnections(conn *Connection, workers int, iterati
 	}
 	wg.Wait()
 }
+
+// countingConn counts writes, each of which is a syscall
+type countingConn struct {
+	net.Conn
+	writes atomic.Int64
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	c.writes.Add(1)
+	return c.Conn.Write(b)
+}
+
+// noOpMessage is the memcached meta no-op, the smallest request and response
+type noOpMessage struct{}
+
+func (noOpMessage) WriteRequest(w *bufio.Writer) error {
+	_, err := w.WriteString("mn\r\n")
+	return err
+}
+
+func (noOpMessage) ReadResponse(r *bufio.Reader) error {
+	_, err := r.ReadSlice('\n')
+	return err
+}
+
+func BenchmarkConnection(b *testing.B) {
+	for _, workers := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("%d workers", workers), func(b *testing.B) {
+			benchConnection(b, workers)
+		})
+	}
+}
+
+func benchConnection(b *testing.B, workers int) {
+	fake, err := testutil.NewFakeMemcached()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer fake.Close()
+
+	netConn, err := net.Dial("tcp", fake.Addr())
+	if err != nil {
+		b.Fatal(err)
+	}
+	counting := &countingConn{Conn: netConn}
+	conn := newConnection(counting)
+	defer conn.Close()
+
+	b.ResetTimer()
+	var requests atomic.Int64
+	wg := &sync.WaitGroup{}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for requests.Add(1) <= int64(b.N) {
+				if err := conn.Call(context.Background(), noOpMessage{}); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	b.StopTimer()
+	b.ReportMetric(float64(counting.writes.Load())/float64(b.N), "writes/op")
+}
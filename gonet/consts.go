@@ -3,5 +3,14 @@ package gonet
 import "errors"
 
 var (
-	ErrConnClosed = errors.New("connection closed")
+	ErrConnClosed  = errors.New("connection closed")
+	ErrSend        = errors.New("sending error")
+	ErrReceive     = errors.New("receiving error")
+	ErrCircuitOpen = errors.New("circuit breaker open")
 )
+
+// IsConnectionError reports whether the error was caused by the connection, rather than by the protocol or the caller.
+// The request may or may not have been processed by the server.
+func IsConnectionError(err error) bool {
+	return errors.Is(err, ErrConnClosed) || errors.Is(err, ErrSend) || errors.Is(err, ErrReceive)
+}
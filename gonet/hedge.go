@@ -0,0 +1,197 @@
+package gonet
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HedgePolicy decides when a second copy of a slow request is sent, to cut tail latency.
+// Implementations must be safe for concurrent use.
+type HedgePolicy interface {
+	// Delay after which the hedged request is sent, zero disables hedging for the request.
+	Delay() time.Duration
+	// Observe records the latency of a successful request.
+	Observe(latency time.Duration)
+}
+
+// FixedHedge always hedges after the same delay.
+type FixedHedge struct {
+	After time.Duration
+}
+
+func (h FixedHedge) Delay() time.Duration {
+	return h.After
+}
+
+func (h FixedHedge) Observe(time.Duration) {}
+
+// PercentileHedge hedges requests slower than the given percentile of recently observed latencies, but not sooner than
+// the minimum delay. Hedging is disabled until the window of samples has been filled.
+type PercentileHedge struct {
+	percentile float64
+	minDelay   time.Duration
+
+	lock    sync.Mutex
+	samples []time.Duration
+	next    int
+	filled  bool
+	// Recomputing the percentile on every request is too expensive, it's updated once per window/10 samples
+	sinceUpdate int
+	delay       atomic.Int64
+}
+
+// DefaultHedgeWindow is the number of samples used by NewPercentileHedge when the window isn't positive.
+const DefaultHedgeWindow = 100
+
+// NewPercentileHedge creates a policy hedging after the percentile (0-100) of the last window latencies.
+func NewPercentileHedge(percentile float64, window int, minDelay time.Duration) *PercentileHedge {
+	if window <= 0 {
+		window = DefaultHedgeWindow
+	}
+	return &PercentileHedge{
+		percentile: percentile,
+		minDelay:   minDelay,
+		samples:    make([]time.Duration, window),
+	}
+}
+
+func (h *PercentileHedge) Delay() time.Duration {
+	return time.Duration(h.delay.Load())
+}
+
+func (h *PercentileHedge) Observe(latency time.Duration) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.samples[h.next] = latency
+	h.next = (h.next + 1) % len(h.samples)
+	if h.next == 0 {
+		h.filled = true
+	}
+	if !h.filled {
+		return
+	}
+
+	h.sinceUpdate++
+	if h.delay.Load() != 0 && h.sinceUpdate < max(len(h.samples)/10, 1) {
+		return
+	}
+	h.sinceUpdate = 0
+
+	sorted := slices.Clone(h.samples)
+	slices.Sort(sorted)
+	idx := min(max(int(float64(len(sorted))*h.percentile/100), 0), len(sorted)-1)
+	h.delay.Store(int64(max(sorted[idx], h.minDelay)))
+}
+
+// CallHedged sends a message created by newMsg, and if it doesn't complete within the policy delay, sends another one
+// on a different connection. The message which completed successfully first is returned, the other one is abandoned
+// and its response is discarded by the connection. If no other connection is idle, no hedged request is sent.
+func (c *Client) CallHedged(ctx context.Context, policy HedgePolicy, newMsg func() Message) (Message, error) {
+	if c.breaker == nil {
+		return c.callHedged(ctx, policy, newMsg)
+	}
+
+	done, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	msg, err := c.callHedged(ctx, policy, newMsg)
+	done(err)
+	return msg, err
+}
+
+func (c *Client) callHedged(ctx context.Context, policy HedgePolicy, newMsg func() Message) (Message, error) {
+	start := time.Now()
+
+	msg := newMsg()
+	conn, req, err := c.send(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	var hedgeTimer <-chan time.Time
+	if delay := policy.Delay(); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		hedgeTimer = timer.C
+	}
+
+	var hedgeMsg Message
+	var hedgeReq *PendingMessage
+	// Nil channels block, so each of the requests is waited for only while in flight
+	primaryDone := req.completed
+	var hedgeDone chan struct{}
+	var firstErr error
+	for {
+		select {
+		case <-primaryDone:
+			if req.err == nil {
+				policy.Observe(time.Since(start))
+				return msg, nil
+			}
+			if hedgeDone == nil {
+				return nil, req.err
+			}
+			primaryDone, firstErr = nil, req.err
+
+		case <-hedgeDone:
+			if hedgeReq.err == nil {
+				policy.Observe(time.Since(start))
+				return hedgeMsg, nil
+			}
+			if primaryDone == nil {
+				return nil, firstErr
+			}
+			hedgeDone = nil
+
+		case <-hedgeTimer:
+			hedgeTimer = nil
+			hedgeMsg = newMsg()
+			hedgeReq = c.trySendOther(ctx, hedgeMsg, conn)
+			if hedgeReq != nil {
+				hedgeDone = hedgeReq.completed
+			}
+
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// trySendOther queues the message on an idle connection other than exclude, without waiting for one to be free.
+func (c *Client) trySendOther(ctx context.Context, msg Message, exclude *Connection) *PendingMessage {
+	var skipped *Connection
+	defer func() {
+		if skipped != nil {
+			c.slots <- skipped
+		}
+	}()
+
+	for {
+		select {
+		case conn := <-c.slots:
+			if !conn.IsOpen() {
+				continue
+			}
+			if conn == exclude {
+				skipped = conn
+				continue
+			}
+
+			req, err := conn.Send(ctx, msg)
+			c.slots <- conn
+			if err != nil {
+				return nil
+			}
+			return req
+
+		default:
+			c.requestGrow()
+			return nil
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package gonet
+
+import (
+	"bufio"
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type HedgeSuite struct {
+	BaseSuite
+}
+
+func TestHedgeSuite(t *testing.T) {
+	suite.Run(t, new(HedgeSuite))
+}
+
+// SlowOnceRequestHandler delays handling of the first request only
+type SlowOnceRequestHandler struct {
+	TestRequestHandler
+	delay  time.Duration
+	slowed atomic.Bool
+}
+
+type SlowRequest struct {
+	*TestRequest
+	delay time.Duration
+}
+
+func (h *SlowOnceRequestHandler) ReadRequest(reader *bufio.Reader) (Request, error) {
+	req, err := h.TestRequestHandler.ReadRequest(reader)
+	if err != nil {
+		return nil, err
+	}
+	if h.slowed.CompareAndSwap(false, true) {
+		return &SlowRequest{TestRequest: req.(*TestRequest), delay: h.delay}, nil
+	}
+	return req, nil
+}
+
+func (r *SlowRequest) Handle() {
+	time.Sleep(r.delay)
+	r.TestRequest.Handle()
+}
+
+func (s *HedgeSuite) TestHedgedCall() {
+	h := &SlowOnceRequestHandler{delay: time.Second}
+	th := WithTracking(NewServerFactory(h))
+
+	l := s.SetupListener(th)
+
+	cli, err := NewClient(l.Address().String(), 2, 2)
+	s.Require().NoError(err)
+
+	var created []*TestMessage
+	newMsg := func() Message {
+		msg := &TestMessage{inText: "hello"}
+		created = append(created, msg)
+		return msg
+	}
+
+	start := time.Now()
+	msg, err := cli.CallHedged(context.Background(), FixedHedge{After: 10 * time.Millisecond}, newMsg)
+	s.Require().NoError(err)
+	s.Less(time.Since(start), 500*time.Millisecond)
+
+	s.Require().Len(created, 2)
+	s.Same(created[1], msg)
+	s.Equal("hello", created[1].outText)
+
+	cli.Close()
+	s.Require().NoError(l.Close())
+	th.Wait()
+}
+
+func (s *HedgeSuite) TestNoHedgeWhenFast() {
+	h := &TestRequestHandler{}
+	th := WithTracking(NewServerFactory(h))
+
+	l := s.SetupListener(th)
+
+	cli, err := NewClient(l.Address().String(), 2, 2)
+	s.Require().NoError(err)
+
+	created := 0
+	newMsg := func() Message {
+		created++
+		return &TestMessage{inText: "hello"}
+	}
+
+	msg, err := cli.CallHedged(context.Background(), FixedHedge{After: time.Minute}, newMsg)
+	s.Require().NoError(err)
+	s.Equal("hello", msg.(*TestMessage).outText)
+	s.Equal(1, created)
+
+	cli.Close()
+	s.Require().NoError(l.Close())
+	th.Wait()
+}
+
+func (s *HedgeSuite) TestPercentileHedge() {
+	h := NewPercentileHedge(90, 100, 5*time.Millisecond)
+	s.Equal(time.Duration(0), h.Delay())
+
+	for i := 1; i <= 99; i++ {
+		h.Observe(time.Duration(i) * time.Millisecond)
+	}
+	// Not enough samples yet
+	s.Equal(time.Duration(0), h.Delay())
+
+	h.Observe(100 * time.Millisecond)
+	s.Equal(91*time.Millisecond, h.Delay())
+
+	// Minimum delay applies to fast distributions
+	for i := 0; i < 100; i++ {
+		h.Observe(time.Millisecond)
+	}
+	s.Equal(5*time.Millisecond, h.Delay())
+}
+
+func (s *HedgeSuite) TestPercentileHedgeDefaultWindow() {
+	h := NewPercentileHedge(50, 0, 0)
+	for i := 1; i <= DefaultHedgeWindow; i++ {
+		h.Observe(time.Duration(i) * time.Millisecond)
+	}
+	s.Equal(51*time.Millisecond, h.Delay())
+}
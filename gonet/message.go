@@ -1,21 +1,62 @@
 package gonet
 
-import "bufio"
+import (
+	"bufio"
+	"net"
+)
 
 // Message represents a request and response of a protocol.
+// WriteRequest should not flush the writer, the connection flushes once there are no more requests ready to be written.
 // If any of the methods returns an error the connection will be closed, as it's likely to be in dirty state.
 // Any valid protocol-level errors must be encoded as part of the response, not returned as errors from  Message methods.
+// A message may also be a batch of requests, with a response of any number of lines (e.g. terminated by a sentinel), as
+// long as ReadResponse consumes exactly the response to what WriteRequest wrote.
 type Message interface {
 	WriteRequest(w *bufio.Writer) error
 	ReadResponse(r *bufio.Reader) error
 }
 
+// NoReplyMessage is implemented by messages which may be sent without waiting for a response (e.g. memcached noreply).
+// When SkipResponse returns true, the message completes as soon as the request has been flushed. ReadResponse is still
+// called afterward, to drain anything the server sends anyway (e.g. errors), and must not modify the message.
+type NoReplyMessage interface {
+	Message
+	SkipResponse() bool
+}
+
+// ValidatedMessage is implemented by messages which check their request before it's sent (e.g. memcached keys). An
+// invalid message fails the call without reaching the connection, whereas an error of WriteRequest closes it, failing
+// the other requests written with it.
+type ValidatedMessage interface {
+	Message
+	Validate() error
+}
+
+// AbortableMessage is implemented by messages which pass data from or to the caller while being processed (e.g.
+// streamed values), which may block the connection. When AbortOnCancel returns true and the call is canceled before
+// the message completes, the connection is closed instead of being left to finish it, failing the other requests on it.
+type AbortableMessage interface {
+	Message
+	AbortOnCancel() bool
+}
+
+// BuffersMessage is implemented by messages with large payloads, which are written with vectored I/O (net.Buffers)
+// instead of being copied into the buffered writer.
+type BuffersMessage interface {
+	Message
+	// RequestBuffers appends the whole request to bufs, returns false to fall back to WriteRequest (e.g. for small
+	// payloads, which are cheaper to copy).
+	RequestBuffers(bufs net.Buffers) (net.Buffers, bool)
+}
+
 // PendingMessage represents a Message wile being processed by the Client.
 type PendingMessage struct {
 	msg Message
 
 	// Client-level error, most commonly ErrConnClosed.
 	err error
+	// Completed once flushed, the response is only drained
+	noReply bool
 
 	// Future, triggered when the response has been fully read, or error occurred.
 	completed chan struct{}
@@ -0,0 +1,43 @@
+package memcached_go
+
+import (
+	"context"
+	"memcached-go/gonet"
+	"memcached-go/testutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type HedgeSuite struct {
+	testutil.BaseSuite
+}
+
+func TestHedgeSuite(t *testing.T) {
+	suite.Run(t, new(HedgeSuite))
+}
+
+func (s *HedgeSuite) TestHedgedGet() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 2, 2, WithHedging(gonet.FixedHedge{After: time.Millisecond}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	fake.Put("foo", 7, []byte("bar"))
+	fake.SetDelay(5 * time.Millisecond)
+
+	val, flags, err := cli.Get(context.Background(), "foo")
+	s.Require().NoError(err)
+	s.Equal("bar", string(val))
+	s.Equal(uint16(7), flags)
+	// The hedged copy reaches the server after the primary response, through the second connection
+	s.Eventually(func() bool { return fake.Commands() == 2 }, time.Second, time.Millisecond)
+
+	val, err = cli.GetV(context.Background(), "missing")
+	s.Require().NoError(err)
+	s.Nil(val)
+}
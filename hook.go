@@ -0,0 +1,36 @@
+package memcached_go
+
+import (
+	"context"
+	"time"
+)
+
+// Hook observes every attempt of a command, e.g. for metrics or logging. Hooks are called synchronously, so they must
+// not block.
+type Hook func(ctx context.Context, attempt Attempt)
+
+// Attempt describes a single attempt to execute a command.
+type Attempt struct {
+	Op  string
+	Key string
+
+	// Number starts at 1, higher numbers are retries
+	Number   int
+	Duration time.Duration
+
+	// Client-level error, protocol errors such as a miss are part of the command result instead
+	Err error
+}
+
+// WithHook registers a hook called after every attempt, hooks are called in the order they were registered.
+func WithHook(h Hook) Option {
+	return func(c *Client) {
+		c.hooks = append(c.hooks, h)
+	}
+}
+
+func (c *Client) observe(ctx context.Context, attempt Attempt) {
+	for _, h := range c.hooks {
+		h(ctx, attempt)
+	}
+}
@@ -0,0 +1,251 @@
+// Package httpcache caches full HTTP responses in memcached, as a shared cache in front of handlers.
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"memcached-go"
+	"memcached-go/internal/singleflight"
+	"net/http"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Responses with these statuses are cacheable by default, see RFC 9110
+var cacheableStatuses = []int{
+	http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent, http.StatusMultipleChoices,
+	http.StatusMovedPermanently, http.StatusPermanentRedirect, http.StatusNotFound, http.StatusMethodNotAllowed,
+	http.StatusGone, http.StatusRequestURITooLong, http.StatusNotImplemented,
+}
+
+// Option customizes the Cache created with New.
+type Option func(c *Cache)
+
+// WithVary adds request headers to the cache key, responses varying on other headers are not cached.
+func WithVary(headers ...string) Option {
+	return func(c *Cache) {
+		for _, h := range headers {
+			c.vary = append(c.vary, textproto.CanonicalMIMEHeaderKey(h))
+		}
+	}
+}
+
+// WithDefaultTTL caches responses without max-age or s-maxage, which are not cached by default.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.defaultTTL = ttl
+	}
+}
+
+// WithStaleIfError serves expired responses for up to window after they expire, if the handler fails with a 5xx
+// status. Responses may extend it with the stale-if-error directive.
+func WithStaleIfError(window time.Duration) Option {
+	return func(c *Cache) {
+		c.staleIfError = window
+	}
+}
+
+// Cache is a middleware caching responses of GET and HEAD requests, keyed by method, host, URL and the request headers
+// selected with WithVary. Responses are cached for their s-maxage or max-age, and never if they are no-store, no-cache
+// or private, or set cookies. Requests with no-store or no-cache, or with credentials, are passed to the handler.
+// Concurrent requests for the same response missing in the cache share a single call of the handler, if the response
+// is cacheable and the requests carry no cookies.
+type Cache struct {
+	responses    *memcached_go.Typed[response]
+	vary         []string
+	defaultTTL   time.Duration
+	staleIfError time.Duration
+	flights      singleflight.Group[*response]
+	now          func() time.Time
+}
+
+// response is a cached response.
+type response struct {
+	Status   int
+	Header   http.Header
+	Body     []byte
+	StoredAt time.Time
+	Expires  time.Time
+	// StaleUntil is when the response can no longer be served on error
+	StaleUntil time.Time
+}
+
+func New(cli *memcached_go.Client, opts ...Option) *Cache {
+	c := &Cache{responses: memcached_go.NewTyped(cli, memcached_go.GobCodec[response]{}), now: time.Now}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Cache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !c.cacheableRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := c.key(r)
+		// The cache is best effort, failed reads are misses
+		cached, found, _ := c.responses.Get(r.Context(), key)
+		if found && c.now().Before(cached.Expires) {
+			c.write(w, r, &cached, "HIT")
+			return
+		}
+
+		// Whether the handler was called for this request, rather than for another one
+		var own bool
+		fetch := func() (*response, error) {
+			own = true
+			return c.fetch(r.Context(), next, r, key)
+		}
+		var resp *response
+		if r.Header.Get("Cookie") == "" {
+			var err error
+			resp, _, err = c.flights.Do(r.Context(), key, fetch)
+			if r.Context().Err() != nil {
+				// Gone while waiting for another request
+				return
+			}
+			if err != nil {
+				// The handler panicked while serving another request, this one gets its own call
+				next.ServeHTTP(w, r)
+				return
+			}
+			if _, ok := c.ttl(resp); !own && !ok {
+				resp = nil
+			}
+		}
+		if resp == nil {
+			// The response may depend on the cookies, or the shared one is only meant for another request, e.g. private
+			resp, _ = fetch()
+		}
+		if resp.Status >= 500 && found && c.now().Before(cached.StaleUntil) {
+			c.write(w, r, &cached, "STALE")
+			return
+		}
+		c.write(w, r, resp, "MISS")
+	})
+}
+
+func (c *Cache) cacheableRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if r.Header.Get("Authorization") != "" {
+		return false
+	}
+	cc := parseCacheControl(r.Header.Get("Cache-Control"))
+	return !cc.has("no-store") && !cc.has("no-cache")
+}
+
+// key hashes the method, host, URL and varying headers, which could be longer than a key or contain spaces. The URL of
+// server requests has no host, which keeps virtual hosts apart.
+func (c *Cache) key(r *http.Request) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.Host + " " + r.URL.String()))
+	for _, name := range c.vary {
+		h.Write([]byte("\n" + name + ": " + strings.Join(r.Header.Values(name), ", ")))
+	}
+	return "httpcache:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// fetch calls the handler, and caches the response if it's cacheable.
+func (c *Cache) fetch(ctx context.Context, next http.Handler, r *http.Request, key string) (*response, error) {
+	rec := &recorder{header: http.Header{}}
+	next.ServeHTTP(rec, r)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	now := c.now()
+	resp := &response{Status: rec.status, Header: rec.header, Body: rec.body.Bytes(), StoredAt: now}
+	ttl, ok := c.ttl(resp)
+	if !ok {
+		return resp, nil
+	}
+	resp.Expires = now.Add(ttl)
+	staleIfError := c.staleIfError
+	if window, ok := parseCacheControl(resp.Header.Get("Cache-Control")).seconds("stale-if-error"); ok {
+		staleIfError = max(staleIfError, window)
+	}
+	resp.StaleUntil = resp.Expires.Add(staleIfError)
+	// Best effort, the response is served anyway. Memcached expires values with a precision of seconds, hence the
+	// extra second.
+	_ = c.responses.Set(ctx, key, *resp, ttl+staleIfError+time.Second)
+	return resp, nil
+}
+
+// ttl returns how long the response is fresh, if it's cacheable.
+func (c *Cache) ttl(resp *response) (time.Duration, bool) {
+	if !slices.Contains(cacheableStatuses, resp.Status) || resp.Header.Get("Set-Cookie") != "" {
+		return 0, false
+	}
+	for _, vary := range resp.Header.Values("Vary") {
+		for _, name := range strings.Split(vary, ",") {
+			name = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))
+			if name == "*" || !slices.Contains(c.vary, name) {
+				return 0, false
+			}
+		}
+	}
+
+	cc := parseCacheControl(resp.Header.Get("Cache-Control"))
+	if cc.has("no-store") || cc.has("no-cache") || cc.has("private") {
+		return 0, false
+	}
+	// s-maxage is meant for shared caches, like this one
+	if ttl, ok := cc.seconds("s-maxage"); ok {
+		return ttl, ttl > 0
+	}
+	if ttl, ok := cc.seconds("max-age"); ok {
+		return ttl, ttl > 0
+	}
+	return c.defaultTTL, c.defaultTTL > 0
+}
+
+// write sends the response, marked with X-Cache as a HIT, MISS or STALE.
+func (c *Cache) write(w http.ResponseWriter, r *http.Request, resp *response, status string) {
+	h := w.Header()
+	for name, values := range resp.Header {
+		h[name] = slices.Clone(values)
+	}
+	if status != "MISS" {
+		age := c.now().Sub(resp.StoredAt) / time.Second
+		h.Set("Age", strconv.FormatInt(int64(max(age, 0)), 10))
+	}
+	h.Set("X-Cache", status)
+	w.WriteHeader(resp.Status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(resp.Body)
+	}
+}
+
+// recorder buffers the response of the handler.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(b)
+}
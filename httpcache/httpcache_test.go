@@ -0,0 +1,261 @@
+package httpcache
+
+import (
+	"io"
+	"memcached-go"
+	"memcached-go/testutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type HttpCacheSuite struct {
+	testutil.BaseSuite
+	fake *testutil.FakeMemcached
+	cli  *memcached_go.Client
+}
+
+func TestHttpCacheSuite(t *testing.T) {
+	suite.Run(t, new(HttpCacheSuite))
+}
+
+func (s *HttpCacheSuite) SetupTest() {
+	var err error
+	s.fake, err = testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	s.cli, err = memcached_go.NewClient(s.fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+}
+
+func (s *HttpCacheSuite) TearDownTest() {
+	s.cli.Close()
+	s.fake.Close()
+}
+
+// origin responds with the number of calls so far, and the Cache-Control header of the cc query parameter
+type origin struct {
+	calls  atomic.Int32
+	status atomic.Int32
+}
+
+func (o *origin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	calls := o.calls.Add(1)
+	if cc := r.URL.Query().Get("cc"); cc != "" {
+		w.Header().Set("Cache-Control", cc)
+	}
+	if vary := r.URL.Query().Get("vary"); vary != "" {
+		w.Header().Set("Vary", vary)
+	}
+	if status := o.status.Load(); status != 0 {
+		w.WriteHeader(int(status))
+	}
+	_, _ = io.WriteString(w, r.Header.Get("Accept-Language")+strconv.Itoa(int(calls)))
+}
+
+func serve(handler http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *HttpCacheSuite) TestCacheControl() {
+	o := &origin{}
+	handler := New(s.cli).Middleware(o)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		return serve(handler, httptest.NewRequest(http.MethodGet, url, nil))
+	}
+
+	rec := get("/?cc=max-age=60")
+	s.Equal("1", rec.Body.String())
+	s.Equal("MISS", rec.Header().Get("X-Cache"))
+	rec = get("/?cc=max-age=60")
+	s.Equal("1", rec.Body.String())
+	s.Equal("HIT", rec.Header().Get("X-Cache"))
+	s.Equal("max-age=60", rec.Header().Get("Cache-Control"))
+	s.Equal("0", rec.Header().Get("Age"))
+
+	// HEAD is cached separately, without a body
+	rec = serve(handler, httptest.NewRequest(http.MethodHead, "/?cc=max-age=60", nil))
+	s.Empty(rec.Body.String())
+
+	for _, cc := range []string{"no-store", "private,max-age=60", "no-cache", "max-age=0"} {
+		before := o.calls.Load()
+		get("/?cc=" + cc)
+		get("/?cc=" + cc)
+		s.Equal(before+2, o.calls.Load(), cc)
+	}
+	// Without freshness information
+	before := o.calls.Load()
+	get("/")
+	get("/")
+	s.Equal(before+2, o.calls.Load())
+
+	// Requests which must not be served from the cache
+	req := httptest.NewRequest(http.MethodGet, "/?cc=max-age=60", nil)
+	req.Header.Set("Cache-Control", "no-cache")
+	s.Empty(serve(handler, req).Header().Get("X-Cache"))
+	req = httptest.NewRequest(http.MethodGet, "/?cc=max-age=60", nil)
+	req.Header.Set("Authorization", "Bearer foo")
+	s.Empty(serve(handler, req).Header().Get("X-Cache"))
+	req = httptest.NewRequest(http.MethodPost, "/?cc=max-age=60", nil)
+	s.Empty(serve(handler, req).Header().Get("X-Cache"))
+}
+
+func (s *HttpCacheSuite) TestVary() {
+	o := &origin{}
+	handler := New(s.cli, WithVary("accept-language"), WithDefaultTTL(time.Minute)).Middleware(o)
+
+	get := func(url, lang string) string {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Accept-Language", lang)
+		return serve(handler, req).Body.String()
+	}
+
+	s.Equal("en1", get("/?vary=Accept-Language", "en"))
+	s.Equal("de2", get("/?vary=Accept-Language", "de"))
+	s.Equal("en1", get("/?vary=Accept-Language", "en"))
+
+	// Varying on headers which are not part of the key
+	s.Equal("en3", get("/?vary=Accept-Encoding", "en"))
+	s.Equal("en4", get("/?vary=Accept-Encoding", "en"))
+}
+
+func (s *HttpCacheSuite) TestStaleIfError() {
+	o := &origin{}
+	c := New(s.cli, WithStaleIfError(10*time.Minute))
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	handler := c.Middleware(o)
+
+	get := func() *httptest.ResponseRecorder {
+		return serve(handler, httptest.NewRequest(http.MethodGet, "/?cc=max-age=60", nil))
+	}
+
+	s.Equal("1", get().Body.String())
+	now = now.Add(2 * time.Minute)
+	o.status.Store(http.StatusServiceUnavailable)
+	rec := get()
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal("1", rec.Body.String())
+	s.Equal("STALE", rec.Header().Get("X-Cache"))
+	s.Equal("120", rec.Header().Get("Age"))
+
+	// Refreshed once the handler recovers
+	o.status.Store(0)
+	s.Equal("3", get().Body.String())
+
+	now = now.Add(20 * time.Minute)
+	o.status.Store(http.StatusServiceUnavailable)
+	s.Equal(http.StatusServiceUnavailable, get().Code)
+}
+
+func (s *HttpCacheSuite) TestCoalescing() {
+	var calls atomic.Int32
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	handler := New(s.cli).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			close(entered)
+		}
+		<-release
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = io.WriteString(w, "slow")
+	}))
+
+	var wg sync.WaitGroup
+	bodies := make(chan string, 10)
+	for range cap(bodies) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bodies <- serve(handler, httptest.NewRequest(http.MethodGet, "/", nil)).Body.String()
+		}()
+	}
+	<-entered
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(bodies)
+
+	s.Equal(int32(1), calls.Load())
+	for body := range bodies {
+		s.Equal("slow", body)
+	}
+}
+
+func (s *HttpCacheSuite) TestCoalescingUncacheable() {
+	for _, tc := range []struct {
+		name   string
+		cc     string
+		cookie string
+	}{
+		{name: "private", cc: "private,max-age=60"},
+		{name: "cookie", cc: "max-age=60", cookie: "session=1"},
+	} {
+		s.Run(tc.name, func() {
+			var calls atomic.Int32
+			entered := make(chan struct{})
+			release := make(chan struct{})
+			handler := New(s.cli).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls := calls.Add(1)
+				if calls == 1 {
+					close(entered)
+				}
+				<-release
+				w.Header().Set("Cache-Control", tc.cc)
+				_, _ = io.WriteString(w, strconv.Itoa(int(calls)))
+			}))
+
+			var wg sync.WaitGroup
+			bodies := make(chan string, 10)
+			for range cap(bodies) {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					req := httptest.NewRequest(http.MethodGet, "/"+tc.name, nil)
+					if tc.cookie != "" {
+						req.Header.Set("Cookie", tc.cookie)
+					}
+					bodies <- serve(handler, req).Body.String()
+				}()
+			}
+			<-entered
+			time.Sleep(10 * time.Millisecond)
+			close(release)
+			wg.Wait()
+			close(bodies)
+
+			// Every request got its own response
+			s.Equal(int32(cap(bodies)), calls.Load())
+			seen := map[string]bool{}
+			for body := range bodies {
+				s.False(seen[body], body)
+				seen[body] = true
+			}
+		})
+	}
+}
+
+func (s *HttpCacheSuite) TestVirtualHosts() {
+	handler := New(s.cli).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = io.WriteString(w, r.Host)
+	}))
+
+	get := func(host string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = host
+		return serve(handler, req).Body.String()
+	}
+
+	s.Equal("a.example.com", get("a.example.com"))
+	s.Equal("b.example.com", get("b.example.com"))
+	s.Equal("a.example.com", get("a.example.com"))
+}
@@ -0,0 +1,256 @@
+// Package idempotency records the outcomes of requests by their idempotency keys, so that retried requests get the
+// original response instead of being executed again.
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"memcached-go"
+	"memcached-go/mmc"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInProgress = errors.New("request with the idempotency key is in progress")
+	ErrMismatch   = errors.New("idempotency key reused for another request")
+	ErrLost       = errors.New("idempotency claim lost")
+)
+
+// maxBeginAttempts limits retries of Begin racing with expiring records
+const maxBeginAttempts = 3
+
+// DefaultMaxBodySize is the largest request body the middleware reads to fingerprint requests, see WithMaxBodySize.
+const DefaultMaxBodySize = 1 << 20
+
+// Option customizes the Store created with NewStore.
+type Option func(s *Store)
+
+// WithProcessingTTL sets how long a request may take, one minute by default. If the marker of a request in progress
+// expires, a retry executes the request again.
+func WithProcessingTTL(ttl time.Duration) Option {
+	return func(s *Store) {
+		s.processingTTL = ttl
+	}
+}
+
+// WithMaxBodySize limits the request bodies read by the middleware, DefaultMaxBodySize by default. Larger requests
+// fail with 413 Request Entity Too Large.
+func WithMaxBodySize(size int64) Option {
+	return func(s *Store) {
+		s.maxBodySize = size
+	}
+}
+
+// WithScope keys requests by the scope returned for them as well, e.g. the authenticated caller, so that clients
+// choosing the same idempotency key don't get each other's responses. By default, keys are shared by all requests.
+func WithScope(scope func(r *http.Request) string) Option {
+	return func(s *Store) {
+		s.scope = scope
+	}
+}
+
+// Store keeps records under "idempotency:<hash of the scope and key>".
+type Store struct {
+	cli           *memcached_go.Client
+	codec         memcached_go.Codec[record]
+	ttl           time.Duration
+	processingTTL time.Duration
+	maxBodySize   int64
+	scope         func(r *http.Request) string
+}
+
+// Response is a recorded response.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// record is stored while the request is in progress, with an owner token, and once it's completed, with the response.
+type record struct {
+	// Fingerprint of the request, e.g. its method, path and body
+	Fingerprint string
+	Owner       string
+	Completed   bool
+	Response    Response
+}
+
+// NewStore creates a store keeping responses for ttl, e.g. a day.
+func NewStore(cli *memcached_go.Client, ttl time.Duration, opts ...Option) *Store {
+	s := &Store{
+		cli:           cli,
+		codec:         memcached_go.GobCodec[record]{},
+		ttl:           ttl,
+		processingTTL: time.Minute,
+		maxBodySize:   DefaultMaxBodySize,
+		scope:         func(*http.Request) string { return "" },
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Claim is held by the request executing for an idempotency key, it must be completed or aborted.
+type Claim struct {
+	s     *Store
+	key   string
+	owner string
+	rec   record
+}
+
+// Begin claims the idempotency key within the scope for a request, by adding a processing marker. If the key was used
+// before, it returns the recorded response instead, or fails with ErrInProgress while the other request is in
+// progress. Keys reused for requests with another fingerprint fail with ErrMismatch.
+func (s *Store) Begin(ctx context.Context, scope, idempotencyKey, fingerprint string) (*Claim, *Response, error) {
+	key := s.key(scope, idempotencyKey)
+	owner, err := ownerToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	marker := record{Fingerprint: fingerprint, Owner: owner}
+	data, err := s.codec.Marshal(marker)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal marker: %w", err)
+	}
+
+	for range maxBeginAttempts {
+		err = s.cli.Add(ctx, key, uint16(s.codec.ID()), data, s.processingTTL)
+		if err == nil {
+			return &Claim{s: s, key: key, owner: owner, rec: marker}, nil, nil
+		}
+		if !errors.Is(err, mmc.ErrNotStored) {
+			return nil, nil, fmt.Errorf("begin %s: %w", idempotencyKey, err)
+		}
+
+		existing, _, ok, err := s.load(ctx, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			// Expired meanwhile
+			continue
+		}
+		switch {
+		case existing.Fingerprint != fingerprint:
+			return nil, nil, ErrMismatch
+		case !existing.Completed:
+			return nil, nil, ErrInProgress
+		default:
+			return nil, &existing.Response, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("begin %s: %w", idempotencyKey, ErrInProgress)
+}
+
+// Complete records the response, which is returned to retries from then on.
+func (c *Claim) Complete(ctx context.Context, resp Response) error {
+	rec := c.rec
+	rec.Completed = true
+	rec.Response = resp
+	data, err := c.s.codec.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal response: %w", err)
+	}
+
+	cas, err := c.owned(ctx)
+	switch {
+	case errors.Is(err, mmc.ErrNotFound):
+		// The marker expired, but no other request claimed the key
+		err = c.s.cli.Add(ctx, c.key, uint16(c.s.codec.ID()), data, c.s.ttl)
+		if errors.Is(err, mmc.ErrNotStored) {
+			return ErrLost
+		}
+	case err != nil:
+		return err
+	default:
+		err = c.s.cli.CompareAndSwap(ctx, c.key, uint16(c.s.codec.ID()), data, c.s.ttl, cas)
+		if errors.Is(err, mmc.ErrExists) || errors.Is(err, mmc.ErrNotFound) {
+			return ErrLost
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("complete: %w", err)
+	}
+	return nil
+}
+
+// Abort removes the processing marker, so that a retry executes the request again, e.g. after a transient failure.
+func (c *Claim) Abort(ctx context.Context) error {
+	cas, err := c.owned(ctx)
+	if errors.Is(err, mmc.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = c.s.cli.CompareAndDelete(ctx, c.key, cas)
+	if errors.Is(err, mmc.ErrExists) {
+		return ErrLost
+	}
+	return err
+}
+
+// owned returns the CAS unique of the marker, if it's still held by the claim.
+func (c *Claim) owned(ctx context.Context) (uint64, error) {
+	rec, cas, ok, err := c.s.load(ctx, c.key)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, mmc.ErrNotFound
+	}
+	if rec.Owner != c.owner || rec.Completed {
+		return 0, ErrLost
+	}
+	return cas, nil
+}
+
+func (s *Store) load(ctx context.Context, key string) (record, uint64, bool, error) {
+	var rec record
+	data, flags, cas, err := s.cli.Gets(ctx, key)
+	if err != nil {
+		return rec, 0, false, fmt.Errorf("get %s: %w", key, err)
+	}
+	if data == nil {
+		return rec, 0, false, nil
+	}
+	if uint8(flags) != s.codec.ID() {
+		return rec, 0, false, fmt.Errorf("get %s: %w", key, memcached_go.ErrCodecMismatch)
+	}
+	if err = s.codec.Unmarshal(data, &rec); err != nil {
+		return rec, 0, false, fmt.Errorf("unmarshal %s: %w", key, err)
+	}
+	return rec, cas, true, nil
+}
+
+// key hashes the scope and idempotency key, which is chosen by clients and may not be a valid key. The scope is
+// length-prefixed, so that its end can't be moved into the key.
+func (s *Store) key(scope, idempotencyKey string) string {
+	h := sha256.New()
+	h.Write([]byte(strconv.Itoa(len(scope)) + ":" + scope + idempotencyKey))
+	return "idempotency:" + hex.EncodeToString(h.Sum(nil))
+}
+
+func ownerToken() (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("owner token: %w", err)
+	}
+	return hex.EncodeToString(random), nil
+}
+
+// Fingerprint identifies a request by its method, target (the path and query, see url.URL.RequestURI) and body, to
+// detect keys reused for other requests.
+func Fingerprint(method, target string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + target + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,183 @@
+package idempotency
+
+import (
+	"context"
+	"io"
+	"memcached-go"
+	"memcached-go/testutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type IdempotencySuite struct {
+	testutil.BaseSuite
+	fake *testutil.FakeMemcached
+	cli  *memcached_go.Client
+}
+
+func TestIdempotencySuite(t *testing.T) {
+	suite.Run(t, new(IdempotencySuite))
+}
+
+func (s *IdempotencySuite) SetupTest() {
+	var err error
+	s.fake, err = testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	s.cli, err = memcached_go.NewClient(s.fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+}
+
+func (s *IdempotencySuite) TearDownTest() {
+	s.cli.Close()
+	s.fake.Close()
+}
+
+func (s *IdempotencySuite) TestClaim() {
+	ctx := context.Background()
+	store := NewStore(s.cli, time.Hour)
+
+	claim, recorded, err := store.Begin(ctx, "", "key-1", "payment")
+	s.Require().NoError(err)
+	s.Nil(recorded)
+
+	_, _, err = store.Begin(ctx, "", "key-1", "payment")
+	s.ErrorIs(err, ErrInProgress)
+	_, _, err = store.Begin(ctx, "", "key-1", "refund")
+	s.ErrorIs(err, ErrMismatch)
+
+	resp := Response{Status: http.StatusCreated, Header: http.Header{"Location": {"/payments/1"}}, Body: []byte("ok")}
+	s.Require().NoError(claim.Complete(ctx, resp))
+	s.ErrorIs(claim.Complete(ctx, resp), ErrLost)
+
+	_, recorded, err = store.Begin(ctx, "", "key-1", "payment")
+	s.Require().NoError(err)
+	s.Equal(&resp, recorded)
+
+	// Aborted requests can be retried
+	claim, _, err = store.Begin(ctx, "", "key-2", "payment")
+	s.Require().NoError(err)
+	s.Require().NoError(claim.Abort(ctx))
+	retry, recorded, err := store.Begin(ctx, "", "key-2", "payment")
+	s.Require().NoError(err)
+	s.Nil(recorded)
+	// Only the owner of the marker can complete it
+	s.ErrorIs(claim.Complete(ctx, resp), ErrLost)
+	s.ErrorIs(claim.Abort(ctx), ErrLost)
+	s.NoError(retry.Complete(ctx, resp))
+}
+
+func (s *IdempotencySuite) TestScope() {
+	ctx := context.Background()
+	store := NewStore(s.cli, time.Hour)
+
+	_, _, err := store.Begin(ctx, "alice", "key-1", "payment")
+	s.Require().NoError(err)
+	_, recorded, err := store.Begin(ctx, "bob", "key-1", "payment")
+	s.Require().NoError(err)
+	s.Nil(recorded)
+	// The scope can't be shifted into the key
+	_, _, err = store.Begin(ctx, "alice", "key-1x", "payment")
+	s.Require().NoError(err)
+	_, _, err = store.Begin(ctx, "alicek", "ey-1x", "payment")
+	s.Require().NoError(err)
+}
+
+func (s *IdempotencySuite) TestMiddleware() {
+	var calls atomic.Int32
+	inProgress := make(chan struct{})
+	release := make(chan struct{})
+	handler := NewStore(s.cli, time.Hour).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if string(body) == "slow" {
+			close(inProgress)
+			<-release
+		}
+		if string(body) == "fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Location", "/payments/"+strconv.Itoa(int(n)))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	}))
+
+	serve := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(Header, key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("key-1", "100")
+	s.Equal(http.StatusCreated, rec.Code)
+	s.Equal("/payments/1", rec.Header().Get("Location"))
+
+	rec = serve("key-1", "100")
+	s.Equal(http.StatusCreated, rec.Code)
+	s.Equal("/payments/1", rec.Header().Get("Location"))
+	s.Equal("100", rec.Body.String())
+	s.Equal("true", rec.Header().Get("Idempotent-Replayed"))
+	s.Equal(int32(1), calls.Load())
+
+	s.Equal(http.StatusUnprocessableEntity, serve("key-1", "200").Code)
+	// Without a key, requests are executed every time
+	s.Equal("/payments/2", serve("", "100").Header().Get("Location"))
+
+	// Failed requests are not recorded
+	s.Equal(http.StatusServiceUnavailable, serve("key-2", "fail").Code)
+	s.Equal(http.StatusServiceUnavailable, serve("key-2", "fail").Code)
+	s.Equal(int32(4), calls.Load())
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- serve("key-3", "slow")
+	}()
+	<-inProgress
+	s.Equal(http.StatusConflict, serve("key-3", "slow").Code)
+	close(release)
+	s.Equal(http.StatusCreated, (<-done).Code)
+}
+
+func (s *IdempotencySuite) TestMiddlewareOptions() {
+	var calls atomic.Int32
+	store := NewStore(s.cli, time.Hour, WithMaxBodySize(8), WithScope(func(r *http.Request) string {
+		return r.Header.Get("X-User")
+	}))
+	handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	serve := func(user, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set(Header, "key-1")
+		req.Header.Set("X-User", user)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	s.Equal(http.StatusCreated, serve("alice", "/payments?amount=100", "").Code)
+	s.Equal("true", serve("alice", "/payments?amount=100", "").Header().Get("Idempotent-Replayed"))
+	// The query is part of the fingerprint
+	s.Equal(http.StatusUnprocessableEntity, serve("alice", "/payments?amount=200", "").Code)
+	// Other callers don't share keys
+	rec := serve("bob", "/payments?amount=200", "")
+	s.Equal(http.StatusCreated, rec.Code)
+	s.Empty(rec.Header().Get("Idempotent-Replayed"))
+	s.Equal(int32(2), calls.Load())
+
+	s.Equal(http.StatusRequestEntityTooLarge, serve("carol", "/payments", "123456789").Code)
+	s.Equal(int32(2), calls.Load())
+}
@@ -0,0 +1,117 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// Header carries the idempotency key chosen by the client.
+const Header = "Idempotency-Key"
+
+// Middleware executes requests with an Idempotency-Key header at most once per key. Retries get the recorded response,
+// marked with the Idempotent-Replayed header, or 409 Conflict while the first request is in progress. Reusing a key
+// for another request, by method, path, query and body, is 422 Unprocessable Entity. Responses with a 5xx status are
+// not recorded, so that the request can be retried. Requests without the header are passed through.
+func (s *Store) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey := r.Header.Get(Header)
+		if idempotencyKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBodySize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := Fingerprint(r.Method, r.URL.RequestURI(), body)
+		claim, recorded, err := s.Begin(r.Context(), s.scope(r), idempotencyKey, fingerprint)
+		switch {
+		case errors.Is(err, ErrInProgress):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case errors.Is(err, ErrMismatch):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		case err != nil:
+			// Executing the request without a claim could execute it twice
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		case recorded != nil:
+			replay(w, recorded)
+			return
+		}
+
+		// The outcome is recorded even if the client is gone
+		ctx := context.WithoutCancel(r.Context())
+		rec := &recorder{ResponseWriter: w}
+		completed := false
+		defer func() {
+			// The handler failed or panicked
+			if !completed {
+				_ = claim.Abort(ctx)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			// Nothing written, the server responds with 200 OK
+			rec.status = http.StatusOK
+			rec.header = w.Header().Clone()
+		}
+		if rec.status < 500 {
+			// Best effort, the response is sent already
+			_ = claim.Complete(ctx, Response{Status: rec.status, Header: rec.header, Body: rec.body.Bytes()})
+			completed = true
+		}
+	})
+}
+
+func replay(w http.ResponseWriter, resp *Response) {
+	h := w.Header()
+	for name, values := range resp.Header {
+		h[name] = values
+	}
+	h.Set("Idempotent-Replayed", "true")
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(resp.Body)
+}
+
+// recorder passes the response on, and records it.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+		rec.header = rec.ResponseWriter.Header().Clone()
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *recorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
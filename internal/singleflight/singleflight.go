@@ -0,0 +1,60 @@
+// Package singleflight runs a function once for concurrent callers of the same key.
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrPanicked is returned to the callers waiting for a call which panicked, the panic goes on in the caller running it.
+var ErrPanicked = errors.New("shared call panicked")
+
+// Group deduplicates concurrent calls by key, the zero value is ready to use.
+type Group[T any] struct {
+	lock  sync.Mutex
+	calls map[string]*call[T]
+}
+
+type call[T any] struct {
+	waiters int
+
+	// Result, valid once done is closed
+	done chan struct{}
+	val  T
+	err  error
+}
+
+// Do runs f, unless a call for the key is in flight already, in which case it waits for that call's result or for ctx
+// to be done. Shared reports whether the result was handed to other callers as well, so it mustn't be modified then.
+func (g *Group[T]) Do(ctx context.Context, key string, f func() (T, error)) (val T, shared bool, err error) {
+	g.lock.Lock()
+	if c, ok := g.calls[key]; ok {
+		c.waiters++
+		g.lock.Unlock()
+
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return val, false, ctx.Err()
+		}
+		return c.val, true, c.err
+	}
+	if g.calls == nil {
+		g.calls = map[string]*call[T]{}
+	}
+	c := &call[T]{done: make(chan struct{}), err: ErrPanicked}
+	g.calls[key] = c
+	g.lock.Unlock()
+
+	// Waiters are released even if f panics, the panic goes on to the caller
+	defer func() {
+		g.lock.Lock()
+		delete(g.calls, key)
+		shared = c.waiters > 0
+		g.lock.Unlock()
+		close(c.done)
+	}()
+	c.val, c.err = f()
+	return c.val, false, c.err
+}
@@ -0,0 +1,121 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"memcached-go/testutil"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SingleFlightSuite struct {
+	testutil.BaseSuite
+}
+
+func TestSingleFlightSuite(t *testing.T) {
+	suite.Run(t, new(SingleFlightSuite))
+}
+
+func (s *SingleFlightSuite) TestDo() {
+	var g Group[int]
+	var calls atomic.Int32
+	release := make(chan struct{})
+	f := func() (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	var sharedCount atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, shared, err := g.Do(context.Background(), "key", f)
+			s.NoError(err)
+			s.Equal(42, val)
+			if shared {
+				sharedCount.Add(1)
+			}
+		}()
+	}
+	s.Eventually(func() bool {
+		g.lock.Lock()
+		defer g.lock.Unlock()
+		return g.calls["key"] != nil && g.calls["key"].waiters == 9
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	s.Equal(int32(1), calls.Load())
+	s.Equal(int32(10), sharedCount.Load())
+
+	// Not shared once done
+	val, shared, err := g.Do(context.Background(), "key", func() (int, error) { return 7, nil })
+	s.Require().NoError(err)
+	s.Equal(7, val)
+	s.False(shared)
+}
+
+func (s *SingleFlightSuite) TestPanic() {
+	var g Group[int]
+	started := make(chan struct{})
+	release := make(chan struct{})
+	panicked := make(chan any)
+	go func() {
+		defer func() { panicked <- recover() }()
+		_, _, _ = g.Do(context.Background(), "key", func() (int, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+
+	waiterErr := make(chan error)
+	go func() {
+		_, _, err := g.Do(context.Background(), "key", func() (int, error) { return 0, nil })
+		waiterErr <- err
+	}()
+	s.Eventually(func() bool {
+		g.lock.Lock()
+		defer g.lock.Unlock()
+		return g.calls["key"].waiters == 1
+	}, time.Second, time.Millisecond)
+	close(release)
+
+	s.Equal("boom", <-panicked)
+	s.ErrorIs(<-waiterErr, ErrPanicked)
+
+	// Later calls run again
+	val, _, err := g.Do(context.Background(), "key", func() (int, error) { return 7, nil })
+	s.Require().NoError(err)
+	s.Equal(7, val)
+}
+
+func (s *SingleFlightSuite) TestCanceled() {
+	var g Group[int]
+	release := make(chan struct{})
+	defer close(release)
+	go func() {
+		_, _, _ = g.Do(context.Background(), "key", func() (int, error) {
+			<-release
+			return 0, errors.New("late")
+		})
+	}()
+	s.Eventually(func() bool {
+		g.lock.Lock()
+		defer g.lock.Unlock()
+		return g.calls["key"] != nil
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _, err := g.Do(ctx, "key", func() (int, error) { return 0, nil })
+	s.ErrorIs(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,61 @@
+package memcached_go
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"memcached-go/mmc"
+)
+
+// KeyTransformer maps keys used by callers to keys sent to the server. Keys are validated after the transformation.
+type KeyTransformer func(key string) string
+
+// WithKeyTransformer applies the transformer to the keys of all commands.
+func WithKeyTransformer(t KeyTransformer) Option {
+	return func(c *Client) {
+		c.keyTransformer = t
+	}
+}
+
+// HashingKeys replaces keys which are too long, or which are not printable ASCII, by up to prefixLen printable bytes
+// of the key, kept for readability, and a hash of the whole key. Other keys are kept as is.
+func HashingKeys(prefixLen int) KeyTransformer {
+	// Room for the separator and the hash
+	prefixLen = min(prefixLen, mmc.MaxKeyLength-1-hex.EncodedLen(sha256.Size))
+	return func(key string) string {
+		if len(key) > 0 && len(key) <= mmc.MaxKeyLength && isPrintableASCII(key) {
+			return key
+		}
+
+		hashed := make([]byte, 0, prefixLen+1+hex.EncodedLen(sha256.Size))
+		for i := 0; i < len(key) && len(hashed) < prefixLen; i++ {
+			if isPrintableASCII(key[i : i+1]) {
+				hashed = append(hashed, key[i])
+			}
+		}
+		sum := sha256.Sum256([]byte(key))
+		hashed = append(hashed, '#')
+		hashed = hex.AppendEncode(hashed, sum[:])
+		return string(hashed)
+	}
+}
+
+func isPrintableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] <= ' ' || s[i] >= 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// key transforms and validates the key of a command, internal keys derived from transformed keys, e.g. of chunks,
+// must not go through it again.
+func (c *Client) key(key string) (string, error) {
+	if c.keyTransformer != nil {
+		key = c.keyTransformer(key)
+	}
+	if err := mmc.ValidateKey(key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
@@ -0,0 +1,104 @@
+package memcached_go
+
+import (
+	"context"
+	"memcached-go/mmc"
+	"memcached-go/testutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type KeySuite struct {
+	testutil.BaseSuite
+}
+
+func TestKeySuite(t *testing.T) {
+	suite.Run(t, new(KeySuite))
+}
+
+func (s *KeySuite) TestInvalidKeys() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	for _, key := range []string{"", "foo bar", "foo\r\nflush_all", strings.Repeat("k", 251)} {
+		_, err = cli.GetV(ctx, key)
+		s.ErrorIs(err, mmc.ErrInvalidKey)
+		s.ErrorIs(cli.SetV(ctx, key, []byte("bar")), mmc.ErrInvalidKey)
+		s.ErrorIs(cli.Delete(ctx, key), mmc.ErrInvalidKey)
+		_, err = cli.GetMulti(ctx, []string{"foo", key})
+		s.ErrorIs(err, mmc.ErrInvalidKey)
+
+		p := cli.Pipeline()
+		p.Set("foo", 0, []byte("bar"), 0)
+		p.Delete(key)
+		_, err = p.Exec(ctx)
+		s.ErrorIs(err, mmc.ErrInvalidKey)
+	}
+
+	// Nothing was sent
+	s.Equal(0, fake.Commands())
+}
+
+func (s *KeySuite) TestHashingKeys() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1, WithKeyTransformer(HashingKeys(16)))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	long := "user:" + strings.Repeat("x", 300)
+	keys := []string{"plain", long, "user:jürgen müller", "with space"}
+	for _, key := range keys {
+		s.Require().NoError(cli.SetV(ctx, key, []byte(key)))
+		val, err := cli.GetV(ctx, key)
+		s.Require().NoError(err)
+		s.Equal(key, string(val))
+	}
+
+	// Valid keys are kept, others get a readable prefix
+	_, _, ok := fake.Get("plain")
+	s.True(ok)
+	hashed := HashingKeys(16)(long)
+	s.True(strings.HasPrefix(hashed, "user:xxxxxxxxxxx#"))
+	s.NoError(mmc.ValidateKey(hashed))
+	_, _, ok = fake.Get(hashed)
+	s.True(ok)
+	s.True(strings.HasPrefix(HashingKeys(16)("with space"), "withspace#"))
+
+	items, err := cli.GetMulti(ctx, keys)
+	s.Require().NoError(err)
+	s.Len(items, len(keys))
+	for _, key := range keys {
+		s.Equal(key, string(items[key].Value))
+	}
+}
+
+func (s *KeySuite) TestChunkedLongKey() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1,
+		WithKeyTransformer(HashingKeys(200)), WithChunking(Chunking{ChunkSize: 1000}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	key := strings.Repeat("k", 250)
+	val := []byte(strings.Repeat("0123456789", 250))
+	s.Require().NoError(cli.SetV(ctx, key, val))
+	read, err := cli.GetV(ctx, key)
+	s.Require().NoError(err)
+	s.Equal(val, read)
+}
@@ -0,0 +1,123 @@
+package memcached_go
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// FlagNegative marks negative entries by default, see NegativeCaching.Flag.
+const FlagNegative uint16 = 1 << 13
+
+// DefaultNegativeTTL is short, so that data created after a lookup becomes visible soon.
+const DefaultNegativeTTL = 10 * time.Second
+
+// NegativeCaching configures caching of ErrNotFound results of GetOrLoad loaders, so that lookups of missing data
+// don't reach the loader every time.
+type NegativeCaching struct {
+	// TTL of negative entries, usually shorter than the TTL of values, DefaultNegativeTTL if not positive
+	TTL time.Duration
+	// Flag is the reserved bit marking negative entries, FlagNegative if zero
+	Flag uint16
+}
+
+// WithNegativeCaching makes GetOrLoad cache ErrNotFound results, Get sees negative entries as misses. Flags passed to
+// Set must not use the reserved bit.
+func WithNegativeCaching(cfg NegativeCaching) Option {
+	return func(c *Client) {
+		if cfg.TTL <= 0 {
+			cfg.TTL = DefaultNegativeTTL
+		}
+		if cfg.Flag == 0 {
+			cfg.Flag = FlagNegative
+		}
+		c.negative = &cfg
+		c.reserveFlag("negative caching", cfg.Flag)
+	}
+}
+
+// Loader loads a value missing in the cache, e.g. from a database, and returns ErrNotFound if there is none.
+type Loader func(ctx context.Context) ([]byte, error)
+
+// GetOrLoad returns the cached value, or loads it and caches it for ttl. Concurrent calls for the same key in this
+// process share a single call to the loader, which runs with the context of the first caller. If that context is done
+// first, the callers still waiting call the loader again, with their own contexts.
+// The cache is best effort: if reading or writing it fails, the loaded value is returned anyway.
+// With WithEarlyRefresh, values may be loaded again before they expire.
+func (c *Client) GetOrLoad(ctx context.Context, key string, ttl time.Duration, loader Loader) ([]byte, error) {
+	key, err := c.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	val, flags, err := c.lookup(ctx, key)
+	if err == nil && val != nil && c.tagging != nil && flags&c.tagging.Flag != 0 {
+		var valid bool
+		val, valid, err = c.checkTags(ctx, key, val, nil)
+		if !valid {
+			// Loaded again, like on a miss
+			val = nil
+		}
+		flags &^= c.tagging.Flag
+	}
+	if err == nil && val != nil {
+		switch {
+		case c.negative != nil && flags&c.negative.Flag != 0:
+			return nil, ErrNotFound
+		case c.refresh != nil && flags&c.refresh.Flag != 0:
+			e, unwrapped, err := unwrapEnvelope(val)
+			if err == nil && !c.shouldRefresh(e) {
+				return unwrapped, nil
+			}
+		default:
+			return val, nil
+		}
+	}
+
+	for {
+		// Whether the loader ran with this caller's context
+		var own bool
+		val, shared, err := c.flights.Do(ctx, key, func() ([]byte, error) {
+			own = true
+			return c.load(ctx, key, ttl, loader)
+		})
+		canceled := errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+		if !own && canceled && ctx.Err() == nil {
+			// The caller who ran the loader is gone, but this one isn't
+			continue
+		}
+		if shared {
+			// Callers must be able to modify the value they got, so it's not shared
+			return bytes.Clone(val), err
+		}
+		return val, err
+	}
+}
+
+// load calls the loader, and caches its result.
+func (c *Client) load(ctx context.Context, key string, ttl time.Duration, loader Loader) ([]byte, error) {
+	start := time.Now()
+	val, err := loader(ctx)
+	switch {
+	case err == nil:
+		if c.refresh != nil {
+			e := envelope{delta: time.Since(start)}
+			if ttl > 0 {
+				e.expires = time.Now().Add(ttl)
+			}
+			_ = c.store(ctx, key, c.refresh.Flag, e.wrap(val), ttl)
+		} else {
+			_ = c.store(ctx, key, 0, val, ttl)
+		}
+		return val, nil
+	case errors.Is(err, ErrNotFound):
+		if c.negative != nil {
+			_ = c.store(ctx, key, c.negative.Flag, []byte{}, c.negative.TTL)
+		}
+		return nil, err
+	default:
+		return nil, fmt.Errorf("load %s: %w", key, err)
+	}
+}
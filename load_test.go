@@ -0,0 +1,212 @@
+package memcached_go
+
+import (
+	"context"
+	"errors"
+	"memcached-go/testutil"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LoadSuite struct {
+	testutil.BaseSuite
+}
+
+func TestLoadSuite(t *testing.T) {
+	suite.Run(t, new(LoadSuite))
+}
+
+func (s *LoadSuite) TestSingleFlight() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 4)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) ([]byte, error) {
+		loads.Add(1)
+		<-release
+		return []byte("loaded"), nil
+	}
+
+	ctx := context.Background()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err := cli.GetOrLoad(ctx, "foo", time.Minute, loader)
+			s.NoError(err)
+			s.Equal("loaded", string(val))
+			// Each caller has its own copy
+			val[0] = 'X'
+		}()
+	}
+	s.Eventually(func() bool { return loads.Load() == 1 }, time.Second, time.Millisecond)
+	// Let the others join the flight
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	s.Equal(int32(1), loads.Load())
+
+	// Written back
+	stored, _, ok := fake.Get("foo")
+	s.True(ok)
+	s.Equal("loaded", string(stored))
+	val, err := cli.GetOrLoad(ctx, "foo", time.Minute, func(ctx context.Context) ([]byte, error) {
+		s.Fail("loaded again")
+		return nil, nil
+	})
+	s.Require().NoError(err)
+	s.Equal("loaded", string(val))
+}
+
+func (s *LoadSuite) TestNegativeCaching() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1, WithNegativeCaching(NegativeCaching{TTL: time.Minute}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	var loads int
+	notFound := func(ctx context.Context) ([]byte, error) {
+		loads++
+		return nil, ErrNotFound
+	}
+	for i := 0; i < 3; i++ {
+		_, err = cli.GetOrLoad(ctx, "missing", time.Hour, notFound)
+		s.ErrorIs(err, ErrNotFound)
+	}
+	s.Equal(1, loads)
+
+	// A miss for plain gets
+	val, err := cli.GetV(ctx, "missing")
+	s.Require().NoError(err)
+	s.Nil(val)
+	s.ErrorIs(cli.Set(ctx, "foo", FlagNegative, []byte("bar"), 0), ErrReservedFlags)
+
+	// Other errors are not cached
+	errLoad := errors.New("database down")
+	failing := func(ctx context.Context) ([]byte, error) {
+		loads++
+		return nil, errLoad
+	}
+	for i := 0; i < 2; i++ {
+		_, err = cli.GetOrLoad(ctx, "failing", time.Hour, failing)
+		s.ErrorIs(err, errLoad)
+	}
+	s.Equal(3, loads)
+}
+
+func (s *LoadSuite) TestWithoutNegativeCaching() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	var loads int
+	notFound := func(ctx context.Context) ([]byte, error) {
+		loads++
+		return nil, ErrNotFound
+	}
+	for i := 0; i < 2; i++ {
+		_, err = cli.GetOrLoad(ctx, "missing", time.Hour, notFound)
+		s.ErrorIs(err, ErrNotFound)
+	}
+	s.Equal(2, loads)
+}
+
+func (s *LoadSuite) TestPanickingLoader() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	s.Panics(func() {
+		_, _ = cli.GetOrLoad(ctx, "foo", time.Hour, func(ctx context.Context) ([]byte, error) {
+			panic("boom")
+		})
+	})
+
+	// Later calls for the key aren't stuck behind the panicked one
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	val, err := cli.GetOrLoad(ctx, "foo", time.Hour, func(ctx context.Context) ([]byte, error) {
+		return []byte("bar"), nil
+	})
+	s.Require().NoError(err)
+	s.Equal("bar", string(val))
+}
+
+func (s *LoadSuite) TestDefaultNegativeTTL() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1, WithNegativeCaching(NegativeCaching{}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	s.Equal(DefaultNegativeTTL, cli.negative.TTL)
+}
+
+func (s *LoadSuite) TestCanceledFirstCaller() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 4)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	var loads atomic.Int32
+	loader := func(ctx context.Context) ([]byte, error) {
+		if loads.Add(1) == 1 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return []byte("loaded"), nil
+	}
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := cli.GetOrLoad(first, "foo", time.Minute, loader)
+		firstErr <- err
+	}()
+	s.Eventually(func() bool { return loads.Load() == 1 }, time.Second, time.Millisecond)
+
+	waiterVal := make(chan []byte)
+	go func() {
+		val, err := cli.GetOrLoad(context.Background(), "foo", time.Minute, loader)
+		s.NoError(err)
+		waiterVal <- val
+	}()
+	// Let the waiter join the flight
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	s.ErrorIs(<-firstErr, context.Canceled)
+	// The waiter loaded the value itself
+	s.Equal("loaded", string(<-waiterVal))
+	s.Equal(int32(2), loads.Load())
+}
@@ -0,0 +1,218 @@
+// Package lock provides a distributed lock backed by memcached. The lock is advisory: it doesn't protect the resource
+// by itself, memcached may evict or lose it, and a paused owner may outlive its lease. Resources which must not be
+// modified by a stale owner should check the fencing token of the lease.
+package lock
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"memcached-go"
+	"memcached-go/gonet"
+	"memcached-go/mmc"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrNotAcquired = errors.New("lock is held by another owner")
+	ErrLost        = errors.New("lock lost")
+)
+
+// DefaultBackoff is used between attempts of Acquire, unless overridden with WithBackoff.
+var DefaultBackoff gonet.Backoff = gonet.DecorrelatedJitterBackoff{Base: 50 * time.Millisecond, Max: time.Second}
+
+// Option customizes the Mutex created with New.
+type Option func(m *Mutex)
+
+// WithBackoff sets the delay between attempts of Acquire.
+func WithBackoff(b gonet.Backoff) Option {
+	return func(m *Mutex) {
+		m.backoff = b
+	}
+}
+
+// Mutex is an advisory lock, held by at most one owner at a time for as long as memcached keeps it, see the package
+// documentation. It's stored under "lock:<name>", and its fencing token under "lock:<name>:fence".
+type Mutex struct {
+	cli      *memcached_go.Client
+	key      string
+	fenceKey string
+	ttl      time.Duration
+	backoff  gonet.Backoff
+}
+
+// New creates a lock, leases expire after ttl unless renewed. Memcached expires values with a precision of seconds,
+// so shorter ttls are rounded up to a second.
+func New(cli *memcached_go.Client, name string, ttl time.Duration, opts ...Option) *Mutex {
+	m := &Mutex{
+		cli:      cli,
+		key:      "lock:" + name,
+		fenceKey: "lock:" + name + ":fence",
+		ttl:      max(ttl, time.Second),
+		backoff:  DefaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// TryAcquire takes the lock if it's free, otherwise it fails with ErrNotAcquired.
+func (m *Mutex) TryAcquire(ctx context.Context) (*Lease, error) {
+	token, err := ownerToken()
+	if err != nil {
+		return nil, err
+	}
+	err = m.cli.Add(ctx, m.key, 0, token, m.ttl)
+	if errors.Is(err, mmc.ErrNotStored) {
+		return nil, ErrNotAcquired
+	}
+	if err != nil {
+		return nil, fmt.Errorf("acquire %s: %w", m.key, err)
+	}
+
+	lease := &Lease{m: m, token: token}
+	lease.Fence, err = m.nextFence(ctx)
+	if err != nil {
+		// Without a fencing token the lease is of no use
+		_ = lease.Release(context.WithoutCancel(ctx))
+		return nil, err
+	}
+	return lease, nil
+}
+
+// Acquire waits until it takes the lock, or the context is done.
+func (m *Mutex) Acquire(ctx context.Context) (*Lease, error) {
+	var delay time.Duration
+	for attempt := 1; ; attempt++ {
+		lease, err := m.TryAcquire(ctx)
+		if !errors.Is(err, ErrNotAcquired) {
+			return lease, err
+		}
+
+		delay = m.backoff.Next(attempt, delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// nextFence increments the fencing token. It starts from the current time rather than from 1, so that it keeps
+// increasing if the counter gets evicted.
+func (m *Mutex) nextFence(ctx context.Context) (uint64, error) {
+	fence, err := m.cli.Incr(ctx, m.fenceKey, 1)
+	if !errors.Is(err, mmc.ErrNotFound) {
+		return fence, err
+	}
+
+	fence = uint64(time.Now().UnixNano())
+	err = m.cli.Add(ctx, m.fenceKey, 0, strconv.AppendUint(nil, fence, 10), 0)
+	if errors.Is(err, mmc.ErrNotStored) {
+		// Created concurrently
+		return m.cli.Incr(ctx, m.fenceKey, 1)
+	}
+	return fence, err
+}
+
+func ownerToken() ([]byte, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("owner token: %w", err)
+	}
+	return hex.AppendEncode(nil, random), nil
+}
+
+// Lease is a held lock. Only the owner can renew or release it, which is checked with CAS.
+type Lease struct {
+	m     *Mutex
+	token []byte
+
+	// Fence is the fencing token, it's greater than the tokens of all earlier leases of the lock
+	Fence uint64
+}
+
+// Renew extends the lease by the ttl of the lock, it fails with ErrLost if the lock expired or is held by another
+// owner.
+func (l *Lease) Renew(ctx context.Context) error {
+	cas, err := l.owned(ctx)
+	if err != nil {
+		return err
+	}
+	err = l.m.cli.CompareAndSwap(ctx, l.m.key, 0, l.token, l.m.ttl, cas)
+	if errors.Is(err, mmc.ErrExists) || errors.Is(err, mmc.ErrNotFound) {
+		return ErrLost
+	}
+	if err != nil {
+		return fmt.Errorf("renew %s: %w", l.m.key, err)
+	}
+	return nil
+}
+
+// Release frees the lock, it fails with ErrLost if the lock expired or is held by another owner.
+func (l *Lease) Release(ctx context.Context) error {
+	cas, err := l.owned(ctx)
+	if err != nil {
+		return err
+	}
+	err = l.m.cli.CompareAndDelete(ctx, l.m.key, cas)
+	if errors.Is(err, mmc.ErrExists) {
+		return ErrLost
+	}
+	if err != nil {
+		return fmt.Errorf("release %s: %w", l.m.key, err)
+	}
+	return nil
+}
+
+// owned returns the CAS unique of the lock, if it's still held by the lease.
+func (l *Lease) owned(ctx context.Context) (uint64, error) {
+	token, _, cas, err := l.m.cli.Gets(ctx, l.m.key)
+	if err != nil {
+		return 0, fmt.Errorf("get %s: %w", l.m.key, err)
+	}
+	if !bytes.Equal(token, l.token) {
+		return 0, ErrLost
+	}
+	return cas, nil
+}
+
+// KeepAlive renews the lease in the background, every third of the ttl, until the context is done. Failed renewals
+// are retried until the lease would have expired. The returned channel receives ErrLost if the lease is lost, and is
+// closed once KeepAlive stops, so that the owner can abort its work.
+func (l *Lease) KeepAlive(ctx context.Context) <-chan error {
+	lost := make(chan error, 1)
+	go func() {
+		defer close(lost)
+
+		ticker := time.NewTicker(l.m.ttl / 3)
+		defer ticker.Stop()
+		renewed := time.Now()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+			err := l.Renew(ctx)
+			switch {
+			case err == nil:
+				renewed = time.Now()
+			case ctx.Err() != nil:
+				return
+			case errors.Is(err, ErrLost) || time.Since(renewed) >= l.m.ttl:
+				lost <- fmt.Errorf("keep alive %s: %w", l.m.key, ErrLost)
+				return
+			}
+		}
+	}()
+	return lost
+}
@@ -0,0 +1,133 @@
+package lock
+
+import (
+	"context"
+	"memcached-go"
+	"memcached-go/gonet"
+	"memcached-go/testutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LockSuite struct {
+	testutil.BaseSuite
+	fake *testutil.FakeMemcached
+	cli  *memcached_go.Client
+}
+
+func TestLockSuite(t *testing.T) {
+	suite.Run(t, new(LockSuite))
+}
+
+func (s *LockSuite) SetupTest() {
+	var err error
+	s.fake, err = testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	s.cli, err = memcached_go.NewClient(s.fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+}
+
+func (s *LockSuite) TearDownTest() {
+	s.cli.Close()
+	s.fake.Close()
+}
+
+func (s *LockSuite) TestExclusive() {
+	ctx := context.Background()
+	m := New(s.cli, "cron", time.Minute)
+
+	lease, err := m.TryAcquire(ctx)
+	s.Require().NoError(err)
+	_, err = New(s.cli, "cron", time.Minute).TryAcquire(ctx)
+	s.ErrorIs(err, ErrNotAcquired)
+	// Other locks are independent
+	_, err = New(s.cli, "other", time.Minute).TryAcquire(ctx)
+	s.NoError(err)
+
+	s.Require().NoError(lease.Renew(ctx))
+	s.Require().NoError(lease.Release(ctx))
+	s.ErrorIs(lease.Release(ctx), ErrLost)
+	s.ErrorIs(lease.Renew(ctx), ErrLost)
+
+	next, err := m.TryAcquire(ctx)
+	s.Require().NoError(err)
+	s.Greater(next.Fence, lease.Fence)
+	// A stale lease can't release the lock of the current owner
+	s.ErrorIs(lease.Release(ctx), ErrLost)
+	_, err = m.TryAcquire(ctx)
+	s.ErrorIs(err, ErrNotAcquired)
+}
+
+func (s *LockSuite) TestFenceEvicted() {
+	ctx := context.Background()
+	m := New(s.cli, "cron", time.Minute)
+
+	lease, err := m.TryAcquire(ctx)
+	s.Require().NoError(err)
+	s.Require().NoError(lease.Release(ctx))
+	s.Require().NoError(s.cli.Delete(ctx, "lock:cron:fence"))
+
+	next, err := m.TryAcquire(ctx)
+	s.Require().NoError(err)
+	s.Greater(next.Fence, lease.Fence)
+}
+
+func (s *LockSuite) TestAcquire() {
+	ctx := context.Background()
+	m := New(s.cli, "cron", time.Minute, WithBackoff(gonet.ConstantBackoff{Delay: time.Millisecond}))
+
+	lease, err := m.Acquire(ctx)
+	s.Require().NoError(err)
+
+	acquired := make(chan *Lease)
+	go func() {
+		next, err := m.Acquire(ctx)
+		s.NoError(err)
+		acquired <- next
+	}()
+	select {
+	case <-acquired:
+		s.Fail("acquired a held lock")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	s.Require().NoError(lease.Release(ctx))
+	next := <-acquired
+	s.Require().NotNil(next)
+	s.Greater(next.Fence, lease.Fence)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = m.Acquire(timeoutCtx)
+	s.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func (s *LockSuite) TestKeepAlive() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := New(s.cli, "cron", time.Second)
+
+	lease, err := m.TryAcquire(ctx)
+	s.Require().NoError(err)
+	lost := lease.KeepAlive(ctx)
+
+	// Held past the ttl
+	time.Sleep(1500 * time.Millisecond)
+	_, err = m.TryAcquire(ctx)
+	s.ErrorIs(err, ErrNotAcquired)
+
+	// Taken over, e.g. after a long pause of the owner
+	s.Require().NoError(s.cli.Delete(ctx, "lock:cron"))
+	_, err = m.TryAcquire(ctx)
+	s.Require().NoError(err)
+	select {
+	case err := <-lost:
+		s.ErrorIs(err, ErrLost)
+	case <-time.After(time.Second):
+		s.Fail("lost lease not reported")
+	}
+	_, ok := <-lost
+	s.False(ok)
+}
@@ -0,0 +1,93 @@
+package mmc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+)
+
+var (
+	deleteCmd = []byte("delete ")
+)
+
+type Delete struct {
+	// Request
+	Key []byte
+	// NoReply asks the server not to respond, errors are not reported then. Followed by a no-op, like Set.NoReply.
+	NoReply bool
+
+	// Response
+	Error error
+}
+
+func NewDelete(key string) *Delete {
+	return &Delete{Key: []byte(key)}
+}
+
+func (d *Delete) Validate() error {
+	return validateKey(d.Key)
+}
+
+func (d *Delete) WriteRequest(w *bufio.Writer) error {
+	_, err := w.Write(deleteCmd)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(d.Key)
+	if err != nil {
+		return err
+	}
+
+	if d.NoReply {
+		_, err = w.Write(noReply)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = w.Write(newLine)
+	if err != nil {
+		return err
+	}
+
+	if d.NoReply {
+		_, err = w.Write(metaNoOpCmd)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Delete) SkipResponse() bool {
+	return d.NoReply
+}
+
+func (d *Delete) ReadResponse(r *bufio.Reader) error {
+	if d.NoReply {
+		return readNoReply(r)
+	}
+
+	h, err := respHeader(r)
+	if err != nil {
+		return err
+	}
+
+	err = maybeError(h)
+	if err != nil {
+		d.Error = err
+		return nil
+	}
+
+	if bytes.Equal(h.code, notFound) {
+		d.Error = ErrNotFound
+		return nil
+	}
+
+	if !bytes.Equal(h.code, deleted) {
+		return fmt.Errorf("expected deleted, but got %q: %w", string(h.code), ErrBadResponse)
+	}
+	return nil
+}
@@ -6,41 +6,57 @@ import (
 )
 
 var (
-	getCmd = []byte("get ")
+	getCmd  = []byte("get ")
+	getsCmd = []byte("gets ")
 )
 
+// Get is a single key get, gets if created by NewGets.
 type Get struct {
 	// Request
+	cmd []byte
 	Key []byte
+	// Alloc is optional, it returns a buffer of length n for the value, e.g. from a pool or a caller's slice
+	Alloc func(n int) []byte
 
 	// Response
 	Flags uint16
 	Value []byte
+	// Cas is the CAS unique of the value, set only by gets
+	Cas   uint64
 	Error error
 }
 
 func NewGet(key string) *Get {
-	// todo: validate key
-	return &Get{Key: []byte(key)}
+	return &Get{cmd: getCmd, Key: []byte(key)}
+}
+
+// NewGets gets the value with its CAS unique, which NewCas compares against.
+func NewGets(key string) *Get {
+	g := NewGet(key)
+	g.cmd = getsCmd
+	return g
+}
+
+func (g *Get) Validate() error {
+	return validateKey(g.Key)
 }
 
 func (g *Get) WriteRequest(w *bufio.Writer) error {
-	_, err := w.Write(getCmd)
-	if err != nil {
-		return err
-	}
+	return writeGet(w, g.cmd, g.Key)
+}
 
-	_, err = w.Write(g.Key)
+func writeGet(w *bufio.Writer, cmd, key []byte) error {
+	_, err := w.Write(cmd)
 	if err != nil {
 		return err
 	}
 
-	_, err = w.Write(newLine)
+	_, err = w.Write(key)
 	if err != nil {
 		return err
 	}
 
-	err = w.Flush()
+	_, err = w.Write(newLine)
 	if err != nil {
 		return err
 	}
@@ -49,28 +65,29 @@ func (g *Get) WriteRequest(w *bufio.Writer) error {
 }
 
 func (g *Get) ReadResponse(r *bufio.Reader) error {
-	header, err := respHeader(r)
+	h, err := respHeader(r)
 	if err != nil {
 		return fmt.Errorf("read response header: %w", err)
 	}
 
-	err = maybeError(header)
+	err = maybeError(h)
 	if err != nil {
 		g.Error = err
 		return nil
 	}
 
-	if isEnd(header) {
+	if isEnd(h) {
 		g.Error = ErrMiss
 		return nil
 	}
 
-	flags, val, err := readValue(r, header, g.Key)
+	flags, cas, val, err := readValue(r, h, g.Key, g.Alloc)
 	if err != nil {
 		return err
 	}
 
 	g.Flags = flags
 	g.Value = val
+	g.Cas = cas
 	return nil
 }
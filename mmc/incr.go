@@ -0,0 +1,87 @@
+package mmc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+)
+
+var (
+	incrCmd = []byte("incr ")
+	decrCmd = []byte("decr ")
+)
+
+// Incr increments or decrements a counter, stored as a decimal number. Decrementing below zero results in zero,
+// incrementing wraps around at 64 bits. A missing key is not created, Error is ErrNotFound then.
+type Incr struct {
+	// Request
+	Key   []byte
+	Delta uint64
+	Decr  bool
+
+	// Response
+	Value uint64
+	Error error
+}
+
+func NewIncr(key string, delta uint64) *Incr {
+	return &Incr{Key: []byte(key), Delta: delta}
+}
+
+func NewDecr(key string, delta uint64) *Incr {
+	return &Incr{Key: []byte(key), Delta: delta, Decr: true}
+}
+
+func (i *Incr) Validate() error {
+	return validateKey(i.Key)
+}
+
+func (i *Incr) WriteRequest(w *bufio.Writer) error {
+	cmd := incrCmd
+	if i.Decr {
+		cmd = decrCmd
+	}
+	_, err := w.Write(cmd)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(i.Key)
+	if err != nil {
+		return err
+	}
+
+	err = writeUint(w, " ", i.Delta)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(newLine)
+	return err
+}
+
+func (i *Incr) ReadResponse(r *bufio.Reader) error {
+	h, err := respHeader(r)
+	if err != nil {
+		return err
+	}
+
+	err = maybeError(h)
+	if err != nil {
+		i.Error = err
+		return nil
+	}
+
+	if bytes.Equal(h.code, notFound) {
+		i.Error = ErrNotFound
+		return nil
+	}
+
+	// Decremented values may be padded with spaces, which are left out of the code
+	value, ok := parseUint(h.code, 64)
+	if !ok {
+		return fmt.Errorf("expected a number, but got %q: %w", string(h.code), ErrBadResponse)
+	}
+	i.Value = value
+	return nil
+}
@@ -0,0 +1,30 @@
+package mmc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxKeyLength is the longest key accepted by memcached.
+const MaxKeyLength = 250
+
+var ErrInvalidKey = errors.New("invalid key")
+
+// ValidateKey checks that the key is safe to send in the text protocol: 1 to 250 bytes, without spaces or control
+// characters, which would be interpreted as more keys or as another command. Messages validate their keys again before
+// they are sent, see gonet.ValidatedMessage, an invalid key fails only its own call.
+func ValidateKey(key string) error {
+	return validateKey(key)
+}
+
+func validateKey[K string | []byte](key K) error {
+	if len(key) == 0 || len(key) > MaxKeyLength {
+		return fmt.Errorf("%w: length %d", ErrInvalidKey, len(key))
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+	}
+	return nil
+}
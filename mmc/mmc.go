@@ -6,19 +6,26 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 )
 
 var (
 	newLine = []byte("\r\n")
 	space   = []byte(" ")
+	noReply = []byte(" noreply")
 
 	end        = []byte("END")
 	en         = []byte("EN")
 	endOfValue = []byte("\r\nEND\r\n")
 
-	value  = []byte("VALUE")
-	stored = []byte("STORED")
+	value     = []byte("VALUE")
+	stored    = []byte("STORED")
+	notStored = []byte("NOT_STORED")
+	deleted   = []byte("DELETED")
+	notFound  = []byte("NOT_FOUND")
+	exists    = []byte("EXISTS")
+	touched   = []byte("TOUCHED")
 
 	genError    = []byte("ERROR")
 	clientError = []byte("CLIENT_ERROR")
@@ -29,18 +36,29 @@ var (
 	ErrGenError    = errors.New("memcached error")
 	ErrBadResponse = errors.New("bad memcached response")
 
-	ErrMiss = errors.New("miss")
+	ErrMiss      = errors.New("miss")
+	ErrNotFound  = errors.New("not found")
+	ErrNotStored = errors.New("not stored")
+	ErrExists    = errors.New("exists")
 )
 
-func respHeader(r *bufio.Reader) ([][]byte, error) {
+// header is a response line without the new line, split at the first space. Both parts point to the reader's buffer,
+// so they are valid only until the next read.
+type header struct {
+	code []byte
+	// nil if the line has no parameters
+	params []byte
+}
+
+func respHeader(r *bufio.Reader) (header, error) {
 	// todo: consider ensure enough buffer always available? Premature optimization for realz :)
 	bin, err := r.ReadSlice('\n')
 	if err != nil {
-		return nil, err
+		return header{}, err
 	}
 	bin = dropTrailingNewLine(bin)
-	parts := bytes.SplitN(bin, space, 2)
-	return parts, nil
+	code, params, _ := bytes.Cut(bin, space)
+	return header{code: code, params: params}, nil
 }
 
 func dropTrailingNewLine(in []byte) []byte {
@@ -48,81 +66,173 @@ func dropTrailingNewLine(in []byte) []byte {
 	return in[:len(in)-2]
 }
 
-func maybeError(header [][]byte) error {
+func maybeError(h header) error {
 	// todo: consider terminating the connection after client error
-	if err := maybeClientError(header); err != nil {
+	if err := maybeClientError(h); err != nil {
 		return err
 	}
-	if err := maybeServerError(header); err != nil {
+	if err := maybeServerError(h); err != nil {
 		return err
 	}
-	if err := maybeGenError(header); err != nil {
+	if err := maybeGenError(h); err != nil {
 		return err
 	}
 	return nil
 }
 
-func maybeClientError(header [][]byte) error {
-	return parseErrorX(header, clientError, ErrClientError)
+func maybeClientError(h header) error {
+	return parseErrorX(h, clientError, ErrClientError)
 }
 
-func maybeServerError(header [][]byte) error {
-	return parseErrorX(header, serverError, ErrServerError)
+func maybeServerError(h header) error {
+	return parseErrorX(h, serverError, ErrServerError)
 }
 
-func maybeGenError(header [][]byte) error {
-	return parseErrorX(header, genError, ErrGenError)
+func maybeGenError(h header) error {
+	return parseErrorX(h, genError, ErrGenError)
 }
 
-func parseErrorX(header [][]byte, errorX []byte, err error) error {
-	if bytes.Equal(header[0], errorX) {
-		if len(header) >= 2 {
-			return fmt.Errorf("%w: %s", err, string(header[1]))
+func parseErrorX(h header, errorX []byte, err error) error {
+	if bytes.Equal(h.code, errorX) {
+		if h.params != nil {
+			return fmt.Errorf("%w: %s", err, string(h.params))
 		}
 		return err
 	}
 	return nil
 }
 
-func isEnd(header [][]byte) bool {
-	return bytes.Equal(header[0], end)
+// readNoReply drains the response to a noreply command, which is followed by a no-op (mn). Nothing is sent on success,
+// but memcached still reports some errors, e.g. CLIENT_ERROR for a bad data chunk, which would otherwise be read as the
+// response to the next request. The errors are dropped, as the caller doesn't wait for them.
+func readNoReply(r *bufio.Reader) error {
+	for {
+		h, err := respHeader(r)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(h.code, metaNoOp) {
+			return nil
+		}
+		if maybeError(h) == nil {
+			return fmt.Errorf("expected an error or MN after noreply, but got %q: %w", string(h.code), ErrBadResponse)
+		}
+	}
 }
 
-func readValue(r *bufio.Reader, header [][]byte, key []byte) (uint16, []byte, error) {
-	if !bytes.Equal(header[0], value) {
-		return 0, nil, fmt.Errorf("expected value, but memcached returned %s: %w", string(header[0]), ErrBadResponse)
-	}
-	if len(header) < 2 {
-		return 0, nil, fmt.Errorf("expected 3 more parts after value: %w", ErrBadResponse)
-	}
-	params := bytes.Split(header[1], space)
-	if len(params) != 3 {
-		return 0, nil, fmt.Errorf("expected 3 more parts after value, got %q: %w", string(header[1]), ErrBadResponse)
+func isEnd(h header) bool {
+	return bytes.Equal(h.code, end)
+}
+
+// readValue reads the value of a single key get, alloc is optional and used to obtain the buffer for the value.
+func readValue(r *bufio.Reader, h header, key []byte, alloc func(n int) []byte) (uint16, uint64, []byte, error) {
+	valueKey, flags, length, cas, err := parseValueHeader(h)
+	if err != nil {
+		return 0, 0, nil, err
 	}
-	if !bytes.Equal(params[0], key) {
-		return 0, nil, fmt.Errorf("incorrect key %q, requested %q: %w", string(params[0]), string(key), ErrBadResponse)
+	if !bytes.Equal(valueKey, key) {
+		return 0, 0, nil, fmt.Errorf("incorrect key %q, requested %q: %w", string(valueKey), string(key), ErrBadResponse)
 	}
-	flags, err := strconv.ParseUint(string(params[1]), 10, 16)
-	if err != nil {
-		return 0, nil, fmt.Errorf("invalid flags: %w", ErrBadResponse)
+	var val []byte
+	if alloc != nil {
+		val = alloc(int(length))
+	} else {
+		val = make([]byte, length)
 	}
-	length, err := strconv.ParseUint(string(params[2]), 10, 32)
+	_, err = io.ReadFull(r, val)
 	if err != nil {
-		return 0, nil, fmt.Errorf("invalid length: %w", ErrBadResponse)
+		return 0, 0, nil, fmt.Errorf("failed to read value: %w", ErrBadResponse)
 	}
-	val := make([]byte, length)
-	_, err = io.ReadFull(r, val)
+
+	err = readValueEnd(r)
 	if err != nil {
-		return 0, nil, fmt.Errorf("failed to read value: %w", ErrBadResponse)
+		return 0, 0, nil, err
 	}
+	return flags, cas, val, nil
+}
 
-	ending := make([]byte, len(endOfValue))
-	_, err = io.ReadFull(r, ending)
+// readValueEnd reads the end of a single key get response, following the value.
+func readValueEnd(r *bufio.Reader) error {
+	// Peeking, so that the ending is compared in place
+	ending, err := r.Peek(len(endOfValue))
 	if err != nil {
-		return 0, nil, fmt.Errorf("after value expected \\r\\nEND\\r\\n: %w", ErrBadResponse)
+		return fmt.Errorf("after value expected \\r\\nEND\\r\\n: %w", ErrBadResponse)
 	}
 	if !bytes.Equal(ending, endOfValue) {
-		return 0, nil, fmt.Errorf("after value expected \\r\\nEND\\r\\n, got %q: %w", string(ending), ErrBadResponse)
+		return fmt.Errorf("after value expected \\r\\nEND\\r\\n, got %q: %w", string(ending), ErrBadResponse)
+	}
+	_, _ = r.Discard(len(endOfValue))
+	return nil
+}
+
+// parseValueHeader parses "VALUE <key> <flags> <bytes> [<cas unique>]", the CAS unique is sent only in response to
+// gets. The key points to the reader's buffer, so it's valid only until the next read.
+func parseValueHeader(h header) ([]byte, uint16, uint64, uint64, error) {
+	if !bytes.Equal(h.code, value) {
+		return nil, 0, 0, 0, fmt.Errorf("expected value, but memcached returned %s: %w", string(h.code), ErrBadResponse)
+	}
+	key, rest, ok1 := bytes.Cut(h.params, space)
+	flagsParam, rest, ok2 := bytes.Cut(rest, space)
+	lengthParam, casParam, hasCas := bytes.Cut(rest, space)
+	if !ok1 || !ok2 || bytes.IndexByte(casParam, ' ') >= 0 {
+		return nil, 0, 0, 0, fmt.Errorf("expected 3 or 4 parts after value, got %q: %w", string(h.params), ErrBadResponse)
+	}
+	flags, ok := parseUint(flagsParam, 16)
+	if !ok {
+		return nil, 0, 0, 0, fmt.Errorf("invalid flags: %w", ErrBadResponse)
+	}
+	length, ok := parseUint(lengthParam, 32)
+	if !ok {
+		return nil, 0, 0, 0, fmt.Errorf("invalid length: %w", ErrBadResponse)
+	}
+	var cas uint64
+	if hasCas {
+		cas, ok = parseUint(casParam, 64)
+		if !ok {
+			return nil, 0, 0, 0, fmt.Errorf("invalid cas unique: %w", ErrBadResponse)
+		}
+	}
+	return key, uint16(flags), length, cas, nil
+}
+
+// parseUint parses a decimal number in place, strconv.ParseUint would need a string.
+func parseUint(b []byte, bitSize int) (uint64, bool) {
+	if len(b) == 0 {
+		return 0, false
+	}
+	limit := uint64(1)<<bitSize - 1
+	if bitSize == 64 {
+		limit = math.MaxUint64
+	}
+	var n uint64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		d := uint64(c - '0')
+		if n > (limit-d)/10 {
+			return 0, false
+		}
+		n = n*10 + d
 	}
-	return uint16(flags), val, nil
+	return n, true
+}
+
+// writeUint writes the prefix, e.g. a space, and the number, formatted straight into the writer's free buffer to avoid
+// allocating.
+func writeUint(w *bufio.Writer, prefix string, n uint64) error {
+	buf := w.AvailableBuffer()
+	buf = append(buf, prefix...)
+	buf = strconv.AppendUint(buf, n, 10)
+	_, err := w.Write(buf)
+	return err
+}
+
+// writeInt is like writeUint, for numbers which may be negative.
+func writeInt(w *bufio.Writer, prefix string, n int64) error {
+	buf := w.AvailableBuffer()
+	buf = append(buf, prefix...)
+	buf = strconv.AppendInt(buf, n, 10)
+	_, err := w.Write(buf)
+	return err
 }
@@ -2,10 +2,13 @@ package mmc
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"memcached-go/gonet"
 	"memcached-go/testutil"
+	"strings"
 	"testing"
 	"time"
 
@@ -30,9 +33,6 @@ func (t *testMsg) WriteRequest(w *bufio.Writer) error {
 	if _, err := w.WriteString(t.req); err != nil {
 		return err
 	}
-	if err := w.Flush(); err != nil {
-		return err
-	}
 	return nil
 }
 
@@ -89,4 +89,338 @@ func (s *MmcSuite) TestMmc() {
 	s.NoError(getMsg.Error)
 	s.Equal("baz", string(getMsg.Value))
 	s.Equal(5, int(getMsg.Flags))
+
+	deleteMsg := NewDelete("bar")
+	err = cli.Call(context.Background(), deleteMsg)
+	s.Require().NoError(err)
+	s.NoError(deleteMsg.Error)
+
+	deleteMsg = NewDelete("bar")
+	err = cli.Call(context.Background(), deleteMsg)
+	s.Require().NoError(err)
+	s.ErrorIs(deleteMsg.Error, ErrNotFound)
+
+	setMsg = NewSet("bar", 0, []byte("quiet"), time.Minute)
+	setMsg.NoReply = true
+	err = cli.Call(context.Background(), setMsg)
+	s.Require().NoError(err)
+
+	getMsg = NewGet("bar")
+	err = cli.Call(context.Background(), getMsg)
+	s.Require().NoError(err)
+	s.Equal("quiet", string(getMsg.Value))
+}
+
+func (s *MmcSuite) TestPipelineResponse() {
+	p := NewPipeline([]MetaOp{
+		NewMetaSet("a", 0, []byte("1"), 0),
+		NewMetaSet("b", 0, []byte("2"), 0),
+		NewMetaDelete("c"),
+	})
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	s.Require().NoError(p.WriteRequest(w))
+	s.Require().NoError(w.Flush())
+	s.Equal("ms a 1 F0 T0 q O0\r\n1\r\nms b 1 F0 T0 q O1\r\n2\r\nmd c q O2\r\nmn\r\n", buf.String())
+
+	r := bufio.NewReader(strings.NewReader("NS O1\r\nEX O2\r\nMN\r\n"))
+	s.Require().NoError(p.ReadResponse(r))
+	s.NoError(p.Error)
+	s.Equal(map[int]error{1: ErrNotStored, 2: ErrExists}, p.Failures)
+
+	p = NewPipeline([]MetaOp{NewMetaSet("a", 0, []byte("1"), 0)})
+	r = bufio.NewReader(strings.NewReader("CLIENT_ERROR bad data chunk\r\nMN\r\n"))
+	s.Require().NoError(p.ReadResponse(r))
+	s.ErrorIs(p.Error, ErrClientError)
+
+	r = bufio.NewReader(strings.NewReader("NS O7\r\nMN\r\n"))
+	s.ErrorIs(p.ReadResponse(r), ErrBadResponse)
+}
+
+func (s *MmcSuite) TestMultiGetResponse() {
+	m := NewMultiGet([]string{"a", "b", "c"})
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	s.Require().NoError(m.WriteRequest(w))
+	s.Require().NoError(w.Flush())
+	s.Equal("get a b c\r\n", buf.String())
+
+	r := bufio.NewReader(strings.NewReader("VALUE a 1 3\r\nfoo\r\nVALUE c 0 0\r\n\r\nEND\r\n"))
+	s.Require().NoError(m.ReadResponse(r))
+	s.NoError(m.Error)
+	s.Equal(map[string]Item{"a": {Flags: 1, Value: []byte("foo")}, "c": {Value: []byte{}}}, m.Items)
+
+	r = bufio.NewReader(strings.NewReader("VALUE a 1 3\r\nfooEND\r\n"))
+	s.ErrorIs(m.ReadResponse(r), ErrBadResponse)
+}
+
+func (s *MmcSuite) TestLargeSet() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := gonet.NewConnection(fake.Addr())
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	large := bytes.Repeat([]byte("0123456789"), largeValue/5)
+	setMsg := NewSet("large", 1, large, 0)
+
+	// Written with vectored I/O, the same bytes as the buffered write
+	bufs, ok := setMsg.RequestBuffers(nil)
+	s.Require().True(ok)
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	s.Require().NoError(setMsg.WriteRequest(w))
+	s.Require().NoError(w.Flush())
+	s.Equal(buf.Bytes(), bytes.Join(bufs, nil))
+
+	err = cli.Call(context.Background(), setMsg)
+	s.Require().NoError(err)
+	s.NoError(setMsg.Error)
+
+	getMsg := NewGet("large")
+	err = cli.Call(context.Background(), getMsg)
+	s.Require().NoError(err)
+	s.Equal(large, getMsg.Value)
+
+	_, ok = NewSet("small", 0, []byte("small"), 0).RequestBuffers(nil)
+	s.False(ok)
+}
+
+func (s *MmcSuite) TestNoReplyError() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := gonet.NewConnection(fake.Addr())
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	// An unknown command is rejected with ERROR even with noreply, and so is the value line following it
+	setMsg := &Set{cmd: []byte("bogus "), Key: []byte("foo"), Value: []byte("v"), NoReply: true}
+	s.Require().NoError(cli.Call(context.Background(), setMsg))
+	deleteMsg := NewDelete("foo")
+	deleteMsg.NoReply = true
+	s.Require().NoError(cli.Call(context.Background(), deleteMsg))
+
+	// The error was drained, not read as the response to the next request
+	setMsg = NewSet("foo", 0, []byte("bar"), 0)
+	s.Require().NoError(cli.Call(context.Background(), setMsg))
+	s.NoError(setMsg.Error)
+	getMsg := NewGet("foo")
+	s.Require().NoError(cli.Call(context.Background(), getMsg))
+	s.Equal("bar", string(getMsg.Value))
+	s.True(cli.IsOpen())
+}
+
+// codecBench runs the request and response of a message over in-memory buffers, so that only the codec is measured.
+type codecBench struct {
+	w    *bufio.Writer
+	resp *bytes.Reader
+	r    *bufio.Reader
+}
+
+func newCodecBench(resp string) *codecBench {
+	b := &codecBench{w: bufio.NewWriter(io.Discard), resp: bytes.NewReader([]byte(resp))}
+	b.r = bufio.NewReader(b.resp)
+	return b
+}
+
+func (b *codecBench) run(msg gonet.Message) error {
+	if err := msg.WriteRequest(b.w); err != nil {
+		return err
+	}
+	if err := b.w.Flush(); err != nil {
+		return err
+	}
+	_, _ = b.resp.Seek(0, io.SeekStart)
+	b.r.Reset(b.resp)
+	return msg.ReadResponse(b.r)
+}
+
+func getHit() (*codecBench, func() error) {
+	b := newCodecBench("VALUE foo 3 20\r\nvalue-blahblahblah-1\r\nEND\r\n")
+	buf := make([]byte, 64)
+	getMsg := NewGet("foo")
+	getMsg.Alloc = func(n int) []byte { return buf[:n] }
+	return b, func() error {
+		getMsg.Value, getMsg.Error = nil, nil
+		return b.run(getMsg)
+	}
+}
+
+func getMiss() (*codecBench, func() error) {
+	b := newCodecBench("END\r\n")
+	getMsg := NewGet("foo")
+	return b, func() error {
+		getMsg.Error = nil
+		return b.run(getMsg)
+	}
+}
+
+func set() (*codecBench, func() error) {
+	b := newCodecBench("STORED\r\n")
+	setMsg := NewSet("foo", 3, []byte("value-blahblahblah-1"), time.Minute)
+	return b, func() error {
+		setMsg.Error = nil
+		return b.run(setMsg)
+	}
+}
+
+var codecBenchmarks = map[string]func() (*codecBench, func() error){
+	"get hit":  getHit,
+	"get miss": getMiss,
+	"set":      set,
+}
+
+func (s *MmcSuite) TestCodecAllocs() {
+	for name, newBench := range codecBenchmarks {
+		_, run := newBench()
+		allocs := testing.AllocsPerRun(100, func() {
+			s.Require().NoError(run())
+		})
+		s.Zero(allocs, name)
+	}
+}
+
+func BenchmarkCodec(b *testing.B) {
+	for name, newBench := range codecBenchmarks {
+		b.Run(name, func(b *testing.B) {
+			_, run := newBench()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := run(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func (s *MmcSuite) TestParseUint() {
+	for in, expected := range map[string]uint64{"0": 0, "42": 42, "65535": 65535, "18446744073709551615": 1<<64 - 1} {
+		n, ok := parseUint([]byte(in), 64)
+		s.True(ok, in)
+		s.Equal(expected, n, in)
+	}
+	for _, in := range []string{"", "-1", "1a", " 1", "18446744073709551616"} {
+		_, ok := parseUint([]byte(in), 64)
+		s.False(ok, in)
+	}
+	_, ok := parseUint([]byte("65536"), 16)
+	s.False(ok)
+}
+
+func (s *MmcSuite) TestValidateKey() {
+	s.NoError(ValidateKey("foo"))
+	s.NoError(ValidateKey(strings.Repeat("k", MaxKeyLength)))
+	s.NoError(ValidateKey("ключ"))
+	invalid := []string{"", strings.Repeat("k", MaxKeyLength+1), "foo bar", "foo\r\nget bar", "foo\x00", "foo\x7f"}
+	for _, key := range invalid {
+		s.ErrorIs(ValidateKey(key), ErrInvalidKey, key)
+	}
+
+	// Messages with invalid keys are not sent, so that they can't inject commands
+	key := "foo\r\nflush_all"
+	msgs := []gonet.ValidatedMessage{
+		NewGet(key), NewGets(key), NewMultiGet([]string{"foo", key}), NewSet(key, 0, nil, 0), NewAdd(key, 0, nil, 0),
+		NewCas(key, 0, nil, 0, 1), NewDelete(key), NewIncr(key, 1), NewTouch(key, 0),
+		NewPipeline([]MetaOp{NewMetaSet(key, 0, nil, 0)}), NewSetStream(key, 0, strings.NewReader(""), 0, 0),
+		NewGetStream(key, io.Discard),
+	}
+	for _, msg := range msgs {
+		s.ErrorIs(msg.Validate(), ErrInvalidKey)
+	}
+	s.Error(NewSetStream("foo", 0, strings.NewReader(""), -1, 0).Validate())
+
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+	cli, err := gonet.NewConnection(fake.Addr())
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	// Only the call with the invalid key fails, the connection stays usable
+	s.ErrorIs(cli.Call(context.Background(), NewSet(key, 0, []byte("bar"), 0)), ErrInvalidKey)
+	s.True(cli.IsOpen())
+	s.Require().NoError(cli.Call(context.Background(), NewSet("foo", 0, []byte("bar"), 0)))
+	s.Equal(1, fake.Commands())
+}
+
+func (s *MmcSuite) TestCas() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := gonet.NewConnection(fake.Addr())
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	casMsg := NewCas("foo", 0, []byte("bar"), 0, 1)
+	s.Require().NoError(cli.Call(ctx, casMsg))
+	s.ErrorIs(casMsg.Error, ErrNotFound)
+
+	s.Require().NoError(cli.Call(ctx, NewSet("foo", 1, []byte("bar"), 0)))
+	getsMsg := NewGets("foo")
+	s.Require().NoError(cli.Call(ctx, getsMsg))
+	s.Require().NoError(getsMsg.Error)
+	s.Equal("bar", string(getsMsg.Value))
+	s.NotZero(getsMsg.Cas)
+
+	casMsg = NewCas("foo", 2, []byte("baz"), 0, getsMsg.Cas)
+	s.Require().NoError(cli.Call(ctx, casMsg))
+	s.NoError(casMsg.Error)
+	// Modified since
+	casMsg = NewCas("foo", 2, []byte("qux"), 0, getsMsg.Cas)
+	s.Require().NoError(cli.Call(ctx, casMsg))
+	s.ErrorIs(casMsg.Error, ErrExists)
+
+	getsMsg = NewGets("foo")
+	s.Require().NoError(cli.Call(ctx, getsMsg))
+	deleteOp := NewMetaDelete("foo")
+	deleteOp.Cas = getsMsg.Cas + 1
+	p := NewPipeline([]MetaOp{deleteOp})
+	s.Require().NoError(cli.Call(ctx, p))
+	s.Equal(map[int]error{0: ErrExists}, p.Failures)
+	deleteOp.Cas = getsMsg.Cas
+	p = NewPipeline([]MetaOp{deleteOp})
+	s.Require().NoError(cli.Call(ctx, p))
+	s.Empty(p.Failures)
+
+	touchMsg := NewTouch("foo", time.Minute)
+	s.Require().NoError(cli.Call(ctx, touchMsg))
+	s.ErrorIs(touchMsg.Error, ErrNotFound)
+	s.Require().NoError(cli.Call(ctx, NewSet("foo", 0, []byte("bar"), time.Minute)))
+	touchMsg = NewTouch("foo", -time.Second)
+	s.Require().NoError(cli.Call(ctx, touchMsg))
+	s.NoError(touchMsg.Error)
+	getMsg := NewGet("foo")
+	s.Require().NoError(cli.Call(ctx, getMsg))
+	s.ErrorIs(getMsg.Error, ErrMiss)
+}
+
+func (s *MmcSuite) TestCasRequest() {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	s.Require().NoError(NewGets("foo").WriteRequest(w))
+	s.Require().NoError(NewCas("foo", 1, []byte("bar"), time.Minute, 42).WriteRequest(w))
+	s.Require().NoError(w.Flush())
+	s.Equal("gets foo\r\ncas foo 1 60 3 42\r\nbar\r\n", buf.String())
+
+	// Vectored I/O writes the CAS unique too
+	large := bytes.Repeat([]byte("0"), largeValue)
+	bufs, ok := NewCas("foo", 1, large, 0, 42).RequestBuffers(nil)
+	s.Require().True(ok)
+	s.Equal("cas foo 1 0 16384 42\r\n", string(bufs[0]))
+
+	getMsg := NewGets("foo")
+	r := bufio.NewReader(strings.NewReader("VALUE foo 1 3 42\r\nbar\r\nEND\r\n"))
+	s.Require().NoError(getMsg.ReadResponse(r))
+	s.Equal(uint64(42), getMsg.Cas)
+	r = bufio.NewReader(strings.NewReader("VALUE foo 1 3 42 7\r\nbar\r\nEND\r\n"))
+	s.ErrorIs(NewGets("foo").ReadResponse(r), ErrBadResponse)
 }
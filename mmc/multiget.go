@@ -0,0 +1,105 @@
+package mmc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Item is a value returned by a multi-key get.
+type Item struct {
+	Flags uint16
+	Value []byte
+}
+
+// MultiGet fetches many keys in one request, missing keys are not included in Items.
+type MultiGet struct {
+	// Request
+	Keys [][]byte
+
+	// Response
+	Items map[string]Item
+	Error error
+}
+
+func NewMultiGet(keys []string) *MultiGet {
+	m := &MultiGet{Keys: make([][]byte, len(keys))}
+	for i, key := range keys {
+		m.Keys[i] = []byte(key)
+	}
+	return m
+}
+
+func (m *MultiGet) Validate() error {
+	for _, key := range m.Keys {
+		if err := validateKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MultiGet) WriteRequest(w *bufio.Writer) error {
+	_, err := w.Write(getCmd)
+	if err != nil {
+		return err
+	}
+
+	for i, key := range m.Keys {
+		if i > 0 {
+			_, err = w.Write(space)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = w.Write(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = w.Write(newLine)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *MultiGet) ReadResponse(r *bufio.Reader) error {
+	m.Items = make(map[string]Item, len(m.Keys))
+	for {
+		h, err := respHeader(r)
+		if err != nil {
+			return fmt.Errorf("read response header: %w", err)
+		}
+
+		err = maybeError(h)
+		if err != nil {
+			m.Error = err
+			return nil
+		}
+
+		if isEnd(h) {
+			return nil
+		}
+
+		valueKey, flags, length, _, err := parseValueHeader(h)
+		if err != nil {
+			return err
+		}
+		// Copy the key before reading more, it points to the reader's buffer
+		key := string(valueKey)
+
+		val := make([]byte, length+uint64(len(newLine)))
+		_, err = io.ReadFull(r, val)
+		if err != nil {
+			return fmt.Errorf("failed to read value: %w", ErrBadResponse)
+		}
+		if !bytes.Equal(val[length:], newLine) {
+			return fmt.Errorf("after value expected \\r\\n, got %q: %w", string(val[length:]), ErrBadResponse)
+		}
+		m.Items[key] = Item{Flags: flags, Value: val[:length:length]}
+	}
+}
@@ -0,0 +1,225 @@
+package mmc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"time"
+)
+
+var (
+	metaSetCmd    = []byte("ms ")
+	metaDeleteCmd = []byte("md ")
+	metaNoOpCmd   = []byte("mn\r\n")
+
+	metaNoOp      = []byte("MN")
+	metaNotStored = []byte("NS")
+	metaExists    = []byte("EX")
+	metaNotFound  = []byte("NF")
+)
+
+// MetaOp is a single write in a Pipeline.
+type MetaOp struct {
+	Key     []byte
+	Delete  bool
+	Flags   uint16
+	Value   []byte
+	Exptime int32
+	// Cas makes the op conditional on the CAS unique of the stored value, unless zero. A mismatch fails with
+	// ErrExists, a missing key with ErrNotFound.
+	Cas uint64
+}
+
+func NewMetaSet(key string, flags uint16, value []byte, ttl time.Duration) MetaOp {
+	return MetaOp{Key: []byte(key), Flags: flags, Value: value, Exptime: ttlToExptime(ttl)}
+}
+
+func NewMetaDelete(key string) MetaOp {
+	return MetaOp{Key: []byte(key), Delete: true}
+}
+
+// Pipeline sends meta commands in quiet mode followed by a no-op (mn), so that the server responds only to failures
+// and to the no-op, which marks the end of the response. Failures are matched to commands by their opaque token.
+type Pipeline struct {
+	// Request
+	Ops []MetaOp
+
+	// Response
+	// Failures by the index of the op, successful ops are not included
+	Failures map[int]error
+	// Error reported by the server which can't be attributed to an op, e.g. CLIENT_ERROR
+	Error error
+}
+
+func NewPipeline(ops []MetaOp) *Pipeline {
+	return &Pipeline{Ops: ops}
+}
+
+func (p *Pipeline) Validate() error {
+	for _, op := range p.Ops {
+		if err := validateKey(op.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Pipeline) WriteRequest(w *bufio.Writer) error {
+	for i, op := range p.Ops {
+		var err error
+		if op.Delete {
+			err = writeMetaDelete(w, op, i)
+		} else {
+			err = writeMetaSet(w, op, i)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := w.Write(metaNoOpCmd)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func writeMetaSet(w *bufio.Writer, op MetaOp, opaque int) error {
+	_, err := w.Write(metaSetCmd)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(op.Key)
+	if err != nil {
+		return err
+	}
+
+	err = writeUint(w, " ", uint64(len(op.Value)))
+	if err != nil {
+		return err
+	}
+
+	err = writeUint(w, " F", uint64(op.Flags))
+	if err != nil {
+		return err
+	}
+
+	err = writeInt(w, " T", int64(op.Exptime))
+	if err != nil {
+		return err
+	}
+
+	err = writeMetaCas(w, op)
+	if err != nil {
+		return err
+	}
+
+	err = writeUint(w, " q O", uint64(opaque))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(newLine)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(op.Value)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(newLine)
+	return err
+}
+
+func writeMetaDelete(w *bufio.Writer, op MetaOp, opaque int) error {
+	_, err := w.Write(metaDeleteCmd)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(op.Key)
+	if err != nil {
+		return err
+	}
+
+	err = writeMetaCas(w, op)
+	if err != nil {
+		return err
+	}
+
+	err = writeUint(w, " q O", uint64(opaque))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(newLine)
+	return err
+}
+
+func writeMetaCas(w *bufio.Writer, op MetaOp) error {
+	if op.Cas == 0 {
+		return nil
+	}
+	return writeUint(w, " C", op.Cas)
+}
+
+func (p *Pipeline) ReadResponse(r *bufio.Reader) error {
+	for {
+		h, err := respHeader(r)
+		if err != nil {
+			return fmt.Errorf("read response header: %w", err)
+		}
+
+		if bytes.Equal(h.code, metaNoOp) {
+			return nil
+		}
+
+		err = maybeError(h)
+		if err != nil {
+			// Keep reading until the no-op, to leave the connection in a clean state
+			p.Error = err
+			continue
+		}
+
+		var failure error
+		switch {
+		case bytes.Equal(h.code, metaNotStored):
+			failure = ErrNotStored
+		case bytes.Equal(h.code, metaExists):
+			failure = ErrExists
+		case bytes.Equal(h.code, metaNotFound):
+			failure = ErrNotFound
+		default:
+			return fmt.Errorf("unexpected meta response %q: %w", string(h.code), ErrBadResponse)
+		}
+
+		idx, err := opaqueIndex(h, len(p.Ops))
+		if err != nil {
+			return err
+		}
+		if p.Failures == nil {
+			p.Failures = map[int]error{}
+		}
+		p.Failures[idx] = failure
+	}
+}
+
+// opaqueIndex finds the opaque token in the response flags, it's the index of the op.
+func opaqueIndex(h header, ops int) (int, error) {
+	for rest := h.params; len(rest) > 0; {
+		var flag []byte
+		flag, rest, _ = bytes.Cut(rest, space)
+		if len(flag) > 1 && flag[0] == 'O' {
+			idx, ok := parseUint(flag[1:], 32)
+			if !ok || idx >= uint64(ops) {
+				return 0, fmt.Errorf("invalid opaque %q: %w", string(flag), ErrBadResponse)
+			}
+			return int(idx), nil
+		}
+	}
+	return 0, fmt.Errorf("missing opaque in %q %q: %w", string(h.code), string(h.params), ErrBadResponse)
+}
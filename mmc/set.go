@@ -4,79 +4,197 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"net"
+	"strconv"
 	"time"
 )
 
 var (
 	setCmd = []byte("set ")
+	addCmd = []byte("add ")
+	casCmd = []byte("cas ")
 )
 
+// Values from this size up are written with vectored I/O, instead of being copied into the connection's buffer
+const largeValue = 16 * 1024
+
+// Set is a storage command, set unless created by another constructor, e.g. NewAdd.
 type Set struct {
 	// Request
+	cmd     []byte
 	Key     []byte
 	Flags   uint16
 	Value   []byte
 	Exptime int32
+	// Cas is compared with the CAS unique of the stored value, used only by NewCas
+	Cas uint64
+	// NoReply asks the server not to respond, errors are not reported then. A no-op (mn) is sent after the command,
+	// so that errors memcached sends anyway are drained, see gonet.NoReplyMessage.
+	NoReply bool
 
 	// Response
 	Error error
 }
 
 func NewSet(key string, flags uint16, value []byte, ttl time.Duration) *Set {
-	// todo: validate key
-	return &Set{Key: []byte(key), Flags: flags, Value: value, Exptime: ttlToExptime(ttl)}
+	return &Set{cmd: setCmd, Key: []byte(key), Flags: flags, Value: value, Exptime: ttlToExptime(ttl)}
+}
+
+// NewAdd stores the value only if the key doesn't exist yet, otherwise Error is ErrNotStored.
+func NewAdd(key string, flags uint16, value []byte, ttl time.Duration) *Set {
+	s := NewSet(key, flags, value, ttl)
+	s.cmd = addCmd
+	return s
+}
+
+// NewCas stores the value only if it wasn't modified since it was fetched with NewGets, otherwise Error is ErrExists,
+// or ErrNotFound if the key is missing.
+func NewCas(key string, flags uint16, value []byte, ttl time.Duration, cas uint64) *Set {
+	s := NewSet(key, flags, value, ttl)
+	s.cmd = casCmd
+	s.Cas = cas
+	return s
+}
+
+func (s *Set) Validate() error {
+	return validateKey(s.Key)
 }
 
 func (s *Set) WriteRequest(w *bufio.Writer) error {
-	_, err := w.Write(setCmd)
+	err := writeSetHeader(w, s.cmd, s.Key, s.Flags, s.Exptime, uint64(len(s.Value)), s.Cas, s.NoReply)
 	if err != nil {
 		return err
 	}
 
-	_, err = w.Write(s.Key)
+	_, err = w.Write(s.Value)
 	if err != nil {
 		return err
 	}
 
-	// todo: support duration
-	params := fmt.Sprintf(" %d %d %d\r\n", s.Flags, 0, len(s.Value))
-	_, err = w.WriteString(params)
+	_, err = w.Write(newLine)
 	if err != nil {
 		return err
 	}
 
-	_, err = w.Write(s.Value)
+	if s.NoReply {
+		_, err = w.Write(metaNoOpCmd)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeSetHeader writes the storage command line, up to the value. The CAS unique is written only for cas.
+func writeSetHeader(
+	w *bufio.Writer, cmd, key []byte, flags uint16, exptime int32, length, cas uint64, noreply bool,
+) error {
+	_, err := w.Write(cmd)
 	if err != nil {
 		return err
 	}
 
-	_, err = w.Write(newLine)
+	_, err = w.Write(key)
 	if err != nil {
 		return err
 	}
 
-	err = w.Flush()
+	err = writeUint(w, " ", uint64(flags))
 	if err != nil {
 		return err
 	}
 
-	return nil
+	err = writeInt(w, " ", int64(exptime))
+	if err != nil {
+		return err
+	}
+
+	err = writeUint(w, " ", length)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(cmd, casCmd) {
+		err = writeUint(w, " ", cas)
+		if err != nil {
+			return err
+		}
+	}
+
+	if noreply {
+		_, err = w.Write(noReply)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = w.Write(newLine)
+	return err
+}
+
+func (s *Set) RequestBuffers(bufs net.Buffers) (net.Buffers, bool) {
+	if len(s.Value) < largeValue {
+		return bufs, false
+	}
+
+	header := make([]byte, 0, len(s.cmd)+len(s.Key)+len(noReply)+72)
+	header = append(header, s.cmd...)
+	header = append(header, s.Key...)
+	header = append(header, ' ')
+	header = strconv.AppendUint(header, uint64(s.Flags), 10)
+	header = append(header, ' ')
+	header = strconv.AppendInt(header, int64(s.Exptime), 10)
+	header = append(header, ' ')
+	header = strconv.AppendUint(header, uint64(len(s.Value)), 10)
+	if bytes.Equal(s.cmd, casCmd) {
+		header = append(header, ' ')
+		header = strconv.AppendUint(header, s.Cas, 10)
+	}
+	if s.NoReply {
+		header = append(header, noReply...)
+	}
+	header = append(header, newLine...)
+	if s.NoReply {
+		return append(bufs, header, s.Value, newLine, metaNoOpCmd), true
+	}
+	return append(bufs, header, s.Value, newLine), true
+}
+
+func (s *Set) SkipResponse() bool {
+	return s.NoReply
 }
 
 func (s *Set) ReadResponse(r *bufio.Reader) error {
-	header, err := respHeader(r)
+	if s.NoReply {
+		return readNoReply(r)
+	}
+
+	h, err := respHeader(r)
 	if err != nil {
 		return err
 	}
 
-	err = maybeError(header)
+	err = maybeError(h)
 	if err != nil {
 		s.Error = err
 		return nil
 	}
 
-	if !bytes.Equal(header[0], stored) {
-		return fmt.Errorf("expected stored, but got %q: %w", string(header[0]), ErrBadResponse)
+	switch {
+	case bytes.Equal(h.code, notStored):
+		s.Error = ErrNotStored
+		return nil
+	case bytes.Equal(h.code, exists):
+		s.Error = ErrExists
+		return nil
+	case bytes.Equal(h.code, notFound):
+		s.Error = ErrNotFound
+		return nil
+	}
+
+	if !bytes.Equal(h.code, stored) {
+		return fmt.Errorf("expected stored, but got %q: %w", string(h.code), ErrBadResponse)
 	}
 	return nil
 }
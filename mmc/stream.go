@@ -0,0 +1,210 @@
+package mmc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	// Terminates a value which couldn't be read completely, instead of \r\n, so that the server rejects it
+	abortedValue = []byte("!!")
+	// Fills the rest of a value which couldn't be read completely
+	padding = make([]byte, 4096)
+)
+
+// SetStream is a set with the value read from Reader while writing the request, without holding it in memory.
+// If Reader fails, or returns fewer than Size bytes, the value is padded and terminated so that the server rejects it
+// with a client error, and the connection stays usable. Error reports the reader's error then.
+type SetStream struct {
+	// Request
+	Key     []byte
+	Flags   uint16
+	Reader  io.Reader
+	Size    int64
+	Exptime int32
+
+	// Response
+	Error error
+
+	readErr error
+}
+
+func NewSetStream(key string, flags uint16, r io.Reader, size int64, ttl time.Duration) *SetStream {
+	return &SetStream{Key: []byte(key), Flags: flags, Reader: r, Size: size, Exptime: ttlToExptime(ttl)}
+}
+
+func (s *SetStream) Validate() error {
+	if s.Size < 0 {
+		return fmt.Errorf("negative size %d", s.Size)
+	}
+	return validateKey(s.Key)
+}
+
+func (s *SetStream) WriteRequest(w *bufio.Writer) error {
+	s.readErr = nil
+	err := writeSetHeader(w, setCmd, s.Key, s.Flags, s.Exptime, uint64(s.Size), 0, false)
+	if err != nil {
+		return err
+	}
+
+	// Read straight into the writer's buffer, errors of the reader and of the connection are told apart
+	remaining := s.Size
+	for remaining > 0 {
+		if w.Available() == 0 {
+			err = w.Flush()
+			if err != nil {
+				return err
+			}
+		}
+
+		if s.readErr != nil {
+			n := min(int64(w.Available()), int64(len(padding)), remaining)
+			_, _ = w.Write(padding[:n])
+			remaining -= n
+			continue
+		}
+
+		buf := w.AvailableBuffer()
+		buf = buf[:min(int64(cap(buf)), remaining)]
+		n, err := s.Reader.Read(buf)
+		_, _ = w.Write(buf[:n])
+		remaining -= int64(n)
+		if err != nil && (remaining > 0 || !errors.Is(err, io.EOF)) {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			s.readErr = err
+		}
+	}
+
+	if s.readErr != nil {
+		_, err = w.Write(abortedValue)
+		return err
+	}
+	_, err = w.Write(newLine)
+	return err
+}
+
+// AbortOnCancel closes the connection if the call is canceled, as the reader may block it.
+func (s *SetStream) AbortOnCancel() bool {
+	return true
+}
+
+func (s *SetStream) ReadResponse(r *bufio.Reader) error {
+	h, err := respHeader(r)
+	if err != nil {
+		return err
+	}
+
+	if s.readErr != nil {
+		// The server rejected the value, the reader's error is what matters
+		s.Error = fmt.Errorf("read value: %w", s.readErr)
+		return nil
+	}
+
+	err = maybeError(h)
+	if err != nil {
+		s.Error = err
+		return nil
+	}
+
+	if !bytes.Equal(h.code, stored) {
+		return fmt.Errorf("expected stored, but got %q: %w", string(h.code), ErrBadResponse)
+	}
+	return nil
+}
+
+// GetStream is a get with the value written to Writer while reading the response, without holding it in memory.
+// If Writer fails, the rest of the value is still read, so that the connection stays usable. Error reports the
+// writer's error then.
+type GetStream struct {
+	// Request
+	Key    []byte
+	Writer io.Writer
+
+	// Response
+	Flags uint16
+	Size  int64
+	Error error
+}
+
+func NewGetStream(key string, w io.Writer) *GetStream {
+	return &GetStream{Key: []byte(key), Writer: w}
+}
+
+func (g *GetStream) Validate() error {
+	return validateKey(g.Key)
+}
+
+func (g *GetStream) WriteRequest(w *bufio.Writer) error {
+	return writeGet(w, getCmd, g.Key)
+}
+
+// AbortOnCancel closes the connection if the call is canceled, as the writer may block it.
+func (g *GetStream) AbortOnCancel() bool {
+	return true
+}
+
+func (g *GetStream) ReadResponse(r *bufio.Reader) error {
+	h, err := respHeader(r)
+	if err != nil {
+		return fmt.Errorf("read response header: %w", err)
+	}
+
+	err = maybeError(h)
+	if err != nil {
+		g.Error = err
+		return nil
+	}
+
+	if isEnd(h) {
+		g.Error = ErrMiss
+		return nil
+	}
+
+	valueKey, flags, length, _, err := parseValueHeader(h)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(valueKey, g.Key) {
+		return fmt.Errorf("incorrect key %q, requested %q: %w", string(valueKey), string(g.Key), ErrBadResponse)
+	}
+
+	// Pass on the reader's buffer chunk by chunk
+	var writeErr error
+	remaining := int(length)
+	for remaining > 0 {
+		if r.Buffered() == 0 {
+			_, err = r.Peek(1)
+			if err != nil {
+				return fmt.Errorf("failed to read value: %w", ErrBadResponse)
+			}
+		}
+		chunk, _ := r.Peek(min(r.Buffered(), remaining))
+		if writeErr == nil {
+			var n int
+			n, writeErr = g.Writer.Write(chunk)
+			if writeErr == nil && n < len(chunk) {
+				writeErr = io.ErrShortWrite
+			}
+		}
+		_, _ = r.Discard(len(chunk))
+		remaining -= len(chunk)
+	}
+
+	err = readValueEnd(r)
+	if err != nil {
+		return err
+	}
+
+	g.Flags = flags
+	g.Size = int64(length)
+	if writeErr != nil {
+		g.Error = fmt.Errorf("write value: %w", writeErr)
+	}
+	return nil
+}
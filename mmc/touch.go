@@ -0,0 +1,73 @@
+package mmc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"time"
+)
+
+var (
+	touchCmd = []byte("touch ")
+)
+
+// Touch updates the expiration time of a value, without fetching it. A missing key is ErrNotFound.
+type Touch struct {
+	// Request
+	Key     []byte
+	Exptime int32
+
+	// Response
+	Error error
+}
+
+func NewTouch(key string, ttl time.Duration) *Touch {
+	return &Touch{Key: []byte(key), Exptime: ttlToExptime(ttl)}
+}
+
+func (t *Touch) Validate() error {
+	return validateKey(t.Key)
+}
+
+func (t *Touch) WriteRequest(w *bufio.Writer) error {
+	_, err := w.Write(touchCmd)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(t.Key)
+	if err != nil {
+		return err
+	}
+
+	err = writeInt(w, " ", int64(t.Exptime))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(newLine)
+	return err
+}
+
+func (t *Touch) ReadResponse(r *bufio.Reader) error {
+	h, err := respHeader(r)
+	if err != nil {
+		return err
+	}
+
+	err = maybeError(h)
+	if err != nil {
+		t.Error = err
+		return nil
+	}
+
+	if bytes.Equal(h.code, notFound) {
+		t.Error = ErrNotFound
+		return nil
+	}
+
+	if !bytes.Equal(h.code, touched) {
+		return fmt.Errorf("expected touched, but got %q: %w", string(h.code), ErrBadResponse)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package memcached_go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"memcached-go/mmc"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Namespace is a view of the client which keeps its keys apart with a prefix, and can drop all of them at once.
+// The prefix includes a generation number stored in memcached, invalidation increments it, so that the keys of earlier
+// generations are no longer reachable and eventually get evicted. Generations are cached for a while, so other
+// processes see an invalidation once their cached generation expires.
+// Keys are validated with the prefix, so they must be shorter than the server's limit by the prefix length.
+type Namespace struct {
+	cli      *Client
+	name     string
+	genKey   string
+	cacheFor time.Duration
+
+	lock    sync.Mutex
+	gen     uint64
+	expires time.Time
+}
+
+// Namespace creates a view of the client, the generation is fetched at most once per cacheFor. The name must be valid
+// as a key prefix, see mmc.ValidateKey.
+func (c *Client) Namespace(name string, cacheFor time.Duration) (*Namespace, error) {
+	genKey := name + ":gen"
+	if _, err := c.key(genKey); err != nil {
+		return nil, fmt.Errorf("namespace %q: %w", name, err)
+	}
+	return &Namespace{cli: c, name: name, genKey: genKey, cacheFor: cacheFor}, nil
+}
+
+func (n *Namespace) Get(ctx context.Context, key string) ([]byte, uint16, error) {
+	nsKey, err := n.key(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	return n.cli.Get(ctx, nsKey)
+}
+
+func (n *Namespace) GetV(ctx context.Context, key string) ([]byte, error) {
+	val, _, err := n.Get(ctx, key)
+	return val, err
+}
+
+func (n *Namespace) Set(ctx context.Context, key string, flags uint16, val []byte, ttl time.Duration) error {
+	nsKey, err := n.key(ctx, key)
+	if err != nil {
+		return err
+	}
+	return n.cli.Set(ctx, nsKey, flags, val, ttl)
+}
+
+func (n *Namespace) SetV(ctx context.Context, key string, val []byte) error {
+	return n.Set(ctx, key, 0, val, 0)
+}
+
+func (n *Namespace) Delete(ctx context.Context, key string) error {
+	nsKey, err := n.key(ctx, key)
+	if err != nil {
+		return err
+	}
+	return n.cli.Delete(ctx, nsKey)
+}
+
+// Invalidate drops all keys of the namespace, by moving on to the next generation.
+func (n *Namespace) Invalidate(ctx context.Context) error {
+	gen, err := n.cli.Incr(ctx, n.genKey, 1)
+	if errors.Is(err, mmc.ErrNotFound) {
+		// Evicted, a new generation is created anyway
+		gen, err = n.create(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("invalidate namespace %s: %w", n.name, err)
+	}
+	n.store(gen)
+	return nil
+}
+
+func (n *Namespace) key(ctx context.Context, key string) (string, error) {
+	gen, err := n.generation(ctx)
+	if err != nil {
+		return "", err
+	}
+	return n.name + ":" + strconv.FormatUint(gen, 10) + ":" + key, nil
+}
+
+func (n *Namespace) generation(ctx context.Context) (uint64, error) {
+	n.lock.Lock()
+	if n.gen != 0 && time.Now().Before(n.expires) {
+		gen := n.gen
+		n.lock.Unlock()
+		return gen, nil
+	}
+	n.lock.Unlock()
+
+	gen, ok, err := n.fetch(ctx)
+	if err == nil && !ok {
+		gen, err = n.create(ctx)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get generation of namespace %s: %w", n.name, err)
+	}
+	n.store(gen)
+	return gen, nil
+}
+
+func (n *Namespace) fetch(ctx context.Context) (uint64, bool, error) {
+	val, err := n.cli.GetV(ctx, n.genKey)
+	if err != nil || val == nil {
+		return 0, false, err
+	}
+	// Counters may be padded with spaces
+	gen, err := strconv.ParseUint(strings.TrimSpace(string(val)), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid generation %q: %w", string(val), err)
+	}
+	return gen, true, nil
+}
+
+// create starts the generations from the current time, rather than from 1, so that if the generation gets evicted,
+// the keys of earlier generations don't become reachable again.
+func (n *Namespace) create(ctx context.Context) (uint64, error) {
+	gen := uint64(time.Now().UnixNano())
+	err := n.cli.Add(ctx, n.genKey, 0, strconv.AppendUint(nil, gen, 10), 0)
+	if errors.Is(err, mmc.ErrNotStored) {
+		// Created concurrently
+		var ok bool
+		gen, ok, err = n.fetch(ctx)
+		if err == nil && !ok {
+			err = errors.New("generation evicted right after it was created")
+		}
+	}
+	return gen, err
+}
+
+// store caches the generation as read from the server, which may be lower than the cached one if it was evicted and
+// created again.
+func (n *Namespace) store(gen uint64) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.gen = gen
+	n.expires = time.Now().Add(n.cacheFor)
+}
@@ -0,0 +1,145 @@
+package memcached_go
+
+import (
+	"context"
+	"memcached-go/mmc"
+	"memcached-go/testutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type NamespaceSuite struct {
+	testutil.BaseSuite
+}
+
+func TestNamespaceSuite(t *testing.T) {
+	suite.Run(t, new(NamespaceSuite))
+}
+
+func (s *NamespaceSuite) TestInvalidate() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	tenant, err := cli.Namespace("tenant-1", time.Hour)
+	s.Require().NoError(err)
+	other, err := cli.Namespace("tenant-2", time.Hour)
+	s.Require().NoError(err)
+	s.Require().NoError(tenant.SetV(ctx, "foo", []byte("bar")))
+	s.Require().NoError(other.SetV(ctx, "foo", []byte("baz")))
+
+	val, err := tenant.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Equal("bar", string(val))
+
+	s.Require().NoError(tenant.Invalidate(ctx))
+	val, err = tenant.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Nil(val)
+
+	// Other namespaces are not affected
+	val, err = other.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Equal("baz", string(val))
+
+	// Views in other processes see it once the cached generation expires
+	cached, err := cli.Namespace("tenant-2", time.Hour)
+	s.Require().NoError(err)
+	uncached, err := cli.Namespace("tenant-2", 0)
+	s.Require().NoError(err)
+	_, err = cached.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Require().NoError(other.Invalidate(ctx))
+	val, err = cached.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Equal("baz", string(val))
+	val, err = uncached.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Nil(val)
+}
+
+func (s *NamespaceSuite) TestEvictedGeneration() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	tenant, err := cli.Namespace("tenant", 0)
+	s.Require().NoError(err)
+	s.Require().NoError(tenant.SetV(ctx, "foo", []byte("bar")))
+	s.Require().NoError(cli.Delete(ctx, "tenant:gen"))
+
+	// Doesn't start over with a generation used before
+	val, err := tenant.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Nil(val)
+
+	s.Require().NoError(tenant.SetV(ctx, "foo", []byte("bar")))
+	s.Require().NoError(cli.Delete(ctx, "tenant:gen"))
+	s.Require().NoError(tenant.Invalidate(ctx))
+	val, err = tenant.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Nil(val)
+}
+
+func (s *NamespaceSuite) TestKeyLength() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	tenant, err := cli.Namespace("tenant", time.Hour)
+	s.Require().NoError(err)
+	s.Require().NoError(tenant.SetV(ctx, strings.Repeat("k", 200), []byte("bar")))
+	s.ErrorIs(tenant.SetV(ctx, strings.Repeat("k", 240), []byte("bar")), mmc.ErrInvalidKey)
+	s.ErrorIs(tenant.SetV(ctx, "foo bar", []byte("bar")), mmc.ErrInvalidKey)
+}
+
+func (s *NamespaceSuite) TestInvalidName() {
+	cli, err := NewClient("localhost:0", 0, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	_, err = cli.Namespace("tenant 1", time.Hour)
+	s.ErrorIs(err, mmc.ErrInvalidKey)
+	_, err = cli.Namespace(strings.Repeat("t", 250), time.Hour)
+	s.ErrorIs(err, mmc.ErrInvalidKey)
+}
+
+func (s *NamespaceSuite) TestLowerGeneration() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	tenant, err := cli.Namespace("tenant", 0)
+	s.Require().NoError(err)
+	s.Require().NoError(tenant.SetV(ctx, "foo", []byte("bar")))
+
+	// Re-created by another process with a lower generation, e.g. due to clock skew
+	fake.Put("tenant:gen", 0, []byte("1"))
+	s.Require().NoError(cli.SetV(ctx, "tenant:1:foo", []byte("baz")))
+	val, err := tenant.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Equal("baz", string(val))
+}
@@ -0,0 +1,220 @@
+package memcached_go
+
+import (
+	"bytes"
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultNearCacheBytes = 16 << 20
+	DefaultNearCacheTTL   = 10 * time.Second
+	// Rough memory taken by an entry, besides the key and the value
+	nearEntryOverhead = 96
+)
+
+// NearCache configures an in-process LRU cache in front of memcached, which serves the hottest keys without a round
+// trip. Entries are written through by Set, and dropped by other writes of this client, but writes of other processes
+// are seen only once the entry expires, so MaxTTL should be short. InvalidateNear drops entries explicitly, e.g. on a
+// notification from another process.
+type NearCache struct {
+	// MaxBytes limits the keys and values held, DefaultNearCacheBytes if zero
+	MaxBytes int
+	// MaxTTL caps the TTL of entries, DefaultNearCacheTTL if zero
+	MaxTTL time.Duration
+}
+
+// WithNearCache serves Get and GetV from an in-process cache, other reads always go to the server.
+func WithNearCache(cfg NearCache) Option {
+	return func(c *Client) {
+		if cfg.MaxBytes <= 0 {
+			cfg.MaxBytes = DefaultNearCacheBytes
+		}
+		if cfg.MaxTTL <= 0 {
+			cfg.MaxTTL = DefaultNearCacheTTL
+		}
+		c.near = newNearCache(cfg)
+	}
+}
+
+// TierStats counts gets served by a cache tier.
+type TierStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// CacheStats counts Get calls by tier, the server tier counts only gets which were not served by the near cache.
+type CacheStats struct {
+	Near   TierStats
+	Server TierStats
+}
+
+type tierCounters struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+func (t *tierCounters) record(hit bool) {
+	if hit {
+		t.hits.Add(1)
+	} else {
+		t.misses.Add(1)
+	}
+}
+
+func (t *tierCounters) stats() TierStats {
+	return TierStats{Hits: t.hits.Load(), Misses: t.misses.Load()}
+}
+
+func (c *Client) CacheStats() CacheStats {
+	return CacheStats{Near: c.nearStats.stats(), Server: c.serverStats.stats()}
+}
+
+// InvalidateNear drops the keys from the near cache of this client, if any.
+func (c *Client) InvalidateNear(keys ...string) {
+	for _, key := range keys {
+		key, err := c.key(key)
+		if err == nil {
+			c.invalidateNear(key)
+		}
+	}
+}
+
+// PurgeNear drops all entries of the near cache of this client, if any.
+func (c *Client) PurgeNear() {
+	if c.near != nil {
+		c.near.purge()
+	}
+}
+
+// invalidateNear drops a key written by this client, it's a no-op without a near cache.
+func (c *Client) invalidateNear(key string) {
+	if c.near != nil {
+		c.near.invalidate(key)
+	}
+}
+
+type nearCache struct {
+	cfg NearCache
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	lru     list.List
+	bytes   int
+	// Incremented by every write, so that a value fetched from the server is not cached if a write raced with it
+	writes uint64
+}
+
+type nearEntry struct {
+	key     string
+	value   []byte
+	flags   uint16
+	expires time.Time
+}
+
+func newNearCache(cfg NearCache) *nearCache {
+	return &nearCache{cfg: cfg, entries: map[string]*list.Element{}}
+}
+
+// get returns a copy of the value, which the caller may modify.
+func (n *nearCache) get(key string) ([]byte, uint16, bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	elem, ok := n.entries[key]
+	if !ok {
+		return nil, 0, false
+	}
+	entry := elem.Value.(*nearEntry)
+	if !time.Now().Before(entry.expires) {
+		n.remove(elem)
+		return nil, 0, false
+	}
+	n.lru.MoveToFront(elem)
+	return bytes.Clone(entry.value), entry.flags, true
+}
+
+// version returns the current write counter, to be passed to fill.
+func (n *nearCache) version() uint64 {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.writes
+}
+
+// fill caches a value fetched from the server, unless there were writes since version was called.
+func (n *nearCache) fill(key string, value []byte, flags uint16, version uint64) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.writes != version {
+		return
+	}
+	n.put(key, value, flags, n.cfg.MaxTTL)
+}
+
+// set caches a value written by this client.
+func (n *nearCache) set(key string, value []byte, flags uint16, ttl time.Duration) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.writes++
+	if ttl < 0 {
+		// Expires right away on the server
+		if elem, ok := n.entries[key]; ok {
+			n.remove(elem)
+		}
+		return
+	}
+	if ttl == 0 || ttl > n.cfg.MaxTTL {
+		ttl = n.cfg.MaxTTL
+	}
+	n.put(key, value, flags, ttl)
+}
+
+func (n *nearCache) invalidate(key string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.writes++
+	if elem, ok := n.entries[key]; ok {
+		n.remove(elem)
+	}
+}
+
+func (n *nearCache) purge() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.writes++
+	clear(n.entries)
+	n.lru.Init()
+	n.bytes = 0
+}
+
+// put stores a copy of the value, evicting the least recently used entries if needed. Must be called with the lock
+// held.
+func (n *nearCache) put(key string, value []byte, flags uint16, ttl time.Duration) {
+	if elem, ok := n.entries[key]; ok {
+		n.remove(elem)
+	}
+	size := entrySize(key, value)
+	if size > n.cfg.MaxBytes {
+		return
+	}
+	for n.bytes+size > n.cfg.MaxBytes {
+		n.remove(n.lru.Back())
+	}
+
+	entry := &nearEntry{key: key, value: bytes.Clone(value), flags: flags, expires: time.Now().Add(ttl)}
+	n.entries[key] = n.lru.PushFront(entry)
+	n.bytes += size
+}
+
+// remove must be called with the lock held.
+func (n *nearCache) remove(elem *list.Element) {
+	entry := n.lru.Remove(elem).(*nearEntry)
+	delete(n.entries, entry.key)
+	n.bytes -= entrySize(entry.key, entry.value)
+}
+
+func entrySize(key string, value []byte) int {
+	return len(key) + len(value) + nearEntryOverhead
+}
@@ -0,0 +1,139 @@
+package memcached_go
+
+import (
+	"context"
+	"fmt"
+	"memcached-go/testutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type NearCacheSuite struct {
+	testutil.BaseSuite
+}
+
+func TestNearCacheSuite(t *testing.T) {
+	suite.Run(t, new(NearCacheSuite))
+}
+
+func (s *NearCacheSuite) TestNearCache() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1, WithNearCache(NearCache{MaxTTL: 100 * time.Millisecond}))
+	s.Require().NoError(err)
+	defer cli.Close()
+	other, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer other.Close()
+
+	ctx := context.Background()
+	fake.Put("foo", 3, []byte("bar"))
+	val, flags, err := cli.Get(ctx, "foo")
+	s.Require().NoError(err)
+	s.Equal("bar", string(val))
+	s.Equal(uint16(3), flags)
+
+	// Served locally, and not affected by changes of the returned value
+	val[0] = 'X'
+	commands := fake.Commands()
+	val, flags, err = cli.Get(ctx, "foo")
+	s.Require().NoError(err)
+	s.Equal("bar", string(val))
+	s.Equal(uint16(3), flags)
+	s.Equal(commands, fake.Commands())
+
+	// Writes of other clients are seen once the entry expires
+	s.Require().NoError(other.SetV(ctx, "foo", []byte("baz")))
+	val, err = cli.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Equal("bar", string(val))
+	s.Eventually(func() bool {
+		val, err = cli.GetV(ctx, "foo")
+		return err == nil && string(val) == "baz"
+	}, time.Second, 10*time.Millisecond)
+
+	// Own writes are seen right away
+	s.Require().NoError(cli.SetV(ctx, "foo", []byte("qux")))
+	commands = fake.Commands()
+	val, err = cli.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Equal("qux", string(val))
+	s.Equal(commands, fake.Commands())
+
+	s.Require().NoError(cli.Delete(ctx, "foo"))
+	val, err = cli.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Nil(val)
+
+	// Explicit invalidation
+	s.Require().NoError(cli.SetV(ctx, "foo", []byte("bar")))
+	s.Require().NoError(other.SetV(ctx, "foo", []byte("baz")))
+	cli.InvalidateNear("foo")
+	val, err = cli.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Equal("baz", string(val))
+
+	stats := cli.CacheStats()
+	s.Positive(stats.Near.Hits)
+	s.Positive(stats.Near.Misses)
+	s.Positive(stats.Server.Hits)
+	s.Equal(uint64(1), stats.Server.Misses)
+	s.Equal(stats.Near.Misses, stats.Server.Hits+stats.Server.Misses)
+}
+
+func (s *NearCacheSuite) TestNegativeTTL() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1, WithNearCache(NearCache{MaxTTL: time.Minute}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	// Expires right away, and replaces what was cached before
+	ctx := context.Background()
+	s.Require().NoError(cli.SetV(ctx, "foo", []byte("bar")))
+	s.Require().NoError(cli.Set(ctx, "foo", 0, []byte("baz"), -time.Second))
+	val, err := cli.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Nil(val)
+}
+
+func (s *NearCacheSuite) TestEviction() {
+	n := newNearCache(NearCache{MaxBytes: 3 * (nearEntryOverhead + 10), MaxTTL: time.Minute})
+	for i := 0; i < 3; i++ {
+		n.set(fmt.Sprintf("key-%d", i), []byte("value"), 0, 0)
+	}
+	_, _, ok := n.get("key-0")
+	s.True(ok)
+
+	// The least recently used one is evicted
+	n.set("key-3", []byte("value"), 0, 0)
+	_, _, ok = n.get("key-1")
+	s.False(ok)
+	for _, key := range []string{"key-0", "key-2", "key-3"} {
+		_, _, ok = n.get(key)
+		s.True(ok, key)
+	}
+	s.Equal(3*(nearEntryOverhead+10), n.bytes)
+
+	// Too large
+	n.set("large", make([]byte, 1000), 0, 0)
+	_, _, ok = n.get("large")
+	s.False(ok)
+
+	// Stale fills are dropped
+	version := n.version()
+	n.invalidate("key-0")
+	n.fill("key-0", []byte("stale"), 0, version)
+	_, _, ok = n.get("key-0")
+	s.False(ok)
+
+	// TTL is capped
+	n.set("short", []byte("value"), 0, time.Hour)
+	s.WithinDuration(time.Now().Add(time.Minute), n.entries["short"].Value.(*nearEntry).expires, time.Second)
+}
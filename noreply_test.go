@@ -0,0 +1,64 @@
+package memcached_go
+
+import (
+	"context"
+	"memcached-go/testutil"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type NoReplySuite struct {
+	testutil.BaseSuite
+}
+
+func TestNoReplySuite(t *testing.T) {
+	suite.Run(t, new(NoReplySuite))
+}
+
+func (s *NoReplySuite) TestAsyncWrites() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	// Single connection, so that requests are processed in order
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		s.Require().NoError(cli.SetAsync(ctx, "foo", 3, []byte("bar"), 0))
+	}
+
+	val, flags, err := cli.Get(ctx, "foo")
+	s.Require().NoError(err)
+	s.Equal("bar", string(val))
+	s.Equal(uint16(3), flags)
+
+	s.Require().NoError(cli.DeleteAsync(ctx, "foo"))
+	val, err = cli.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Nil(val)
+	// Each of the async writes is followed by a no-op
+	s.Equal(204, fake.Commands())
+}
+
+func (s *NoReplySuite) TestDelete() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	s.Require().NoError(cli.SetV(ctx, "foo", []byte("bar")))
+	s.Require().NoError(cli.Delete(ctx, "foo"))
+	_, _, ok := fake.Get("foo")
+	s.False(ok)
+
+	// Deleting a missing key is fine
+	s.Require().NoError(cli.Delete(ctx, "foo"))
+}
@@ -0,0 +1,109 @@
+package memcached_go
+
+import (
+	"context"
+	"memcached-go/mmc"
+	"time"
+)
+
+// OpPipeline is reported to hooks for pipelines, it's not retried by default, as it may contain any commands.
+const OpPipeline = "pipeline"
+
+// Pipeline collects writes, which are sent together in quiet mode, so that the server acknowledges only failures.
+// A Pipeline is not safe for concurrent use.
+type Pipeline struct {
+	cli *Client
+	ops []mmc.MetaOp
+	// Keys of the ops, as passed by the caller
+	keys []string
+	// First invalid key or flags, reported by Exec
+	err error
+}
+
+func (c *Client) Pipeline() *Pipeline {
+	return &Pipeline{cli: c}
+}
+
+// Set queues a set of the value as is, without compression or chunking. Flags must not use bits reserved by the
+// client, see ErrReservedFlags.
+func (p *Pipeline) Set(key string, flags uint16, val []byte, ttl time.Duration) {
+	if flags&p.cli.reservedFlags != 0 {
+		if p.err == nil {
+			p.err = ErrReservedFlags
+		}
+		return
+	}
+	serverKey, ok := p.key(key)
+	if ok {
+		p.ops = append(p.ops, mmc.NewMetaSet(serverKey, flags, val, ttl))
+		p.keys = append(p.keys, key)
+	}
+}
+
+func (p *Pipeline) Delete(key string) {
+	serverKey, ok := p.key(key)
+	if ok {
+		p.ops = append(p.ops, mmc.NewMetaDelete(serverKey))
+		p.keys = append(p.keys, key)
+	}
+}
+
+func (p *Pipeline) key(key string) (string, bool) {
+	serverKey, err := p.cli.key(key)
+	if err != nil {
+		if p.err == nil {
+			p.err = err
+		}
+		return "", false
+	}
+	return serverKey, true
+}
+
+// Len returns the number of queued commands.
+func (p *Pipeline) Len() int {
+	return len(p.ops)
+}
+
+// Exec sends all queued commands in a single round trip, and resets the pipeline.
+// Failures are returned by key, e.g. mmc.ErrNotStored, deleting a missing key is not reported in quiet mode.
+// If any key or flags were invalid, nothing is sent and the error is returned.
+func (p *Pipeline) Exec(ctx context.Context) (map[string]error, error) {
+	ops, keys, err := p.ops, p.keys, p.err
+	p.ops, p.keys, p.err = nil, nil, nil
+	if err != nil {
+		return nil, err
+	}
+	if len(ops) == 0 {
+		return nil, nil
+	}
+
+	indexFailures, err := p.cli.execPipeline(ctx, ops)
+	for _, op := range ops {
+		p.cli.invalidateNear(string(op.Key))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var failures map[string]error
+	for idx, failure := range indexFailures {
+		if failures == nil {
+			failures = map[string]error{}
+		}
+		failures[keys[idx]] = failure
+	}
+	return failures, nil
+}
+
+// execPipeline returns failures by the index of the op.
+func (c *Client) execPipeline(ctx context.Context, ops []mmc.MetaOp) (map[int]error, error) {
+	msg := mmc.NewPipeline(ops)
+	err := c.call(ctx, OpPipeline, "", msg)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Error != nil {
+		return nil, msg.Error
+	}
+	return msg.Failures, nil
+}
@@ -0,0 +1,55 @@
+package memcached_go
+
+import (
+	"context"
+	"fmt"
+	"memcached-go/testutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type PipelineSuite struct {
+	testutil.BaseSuite
+}
+
+func TestPipelineSuite(t *testing.T) {
+	suite.Run(t, new(PipelineSuite))
+}
+
+func (s *PipelineSuite) TestPipeline() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	s.Require().NoError(cli.SetV(ctx, "old", []byte("value")))
+
+	p := cli.Pipeline()
+	for i := 0; i < 100; i++ {
+		p.Set(fmt.Sprintf("key-%d", i), uint16(i), []byte(fmt.Sprintf("value-%d", i)), time.Hour)
+	}
+	p.Delete("old")
+	p.Delete("missing")
+	s.Equal(102, p.Len())
+
+	failures, err := p.Exec(ctx)
+	s.Require().NoError(err)
+	s.Empty(failures)
+	s.Equal(0, p.Len())
+
+	for i := 0; i < 100; i++ {
+		val, flags, err := cli.Get(ctx, fmt.Sprintf("key-%d", i))
+		s.Require().NoError(err)
+		s.Equal(fmt.Sprintf("value-%d", i), string(val))
+		s.Equal(uint16(i), flags)
+	}
+	val, err := cli.GetV(ctx, "old")
+	s.Require().NoError(err)
+	s.Nil(val)
+}
@@ -0,0 +1,160 @@
+package memcached_go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"memcached-go/mmc"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// Size classes are powers of two, from 64 bytes up to 1MB, memcached's default item size limit
+	minSizeClassBits = 6
+	maxSizeClassBits = 20
+)
+
+// PooledItem is a value backed by a pooled buffer, see Client.GetPooled.
+type PooledItem struct {
+	Value []byte
+	Flags uint16
+
+	// Nil once released
+	buf   *[]byte
+	class int
+}
+
+// bufferPools hold *[]byte of each size class, only buffers are pooled, items are not reused
+var bufferPools [maxSizeClassBits - minSizeClassBits + 1]sync.Pool
+
+// Release returns the buffer to the pool, the item and its value must not be used afterward. Releasing the item again
+// has no effect, but it must not be released concurrently.
+func (i *PooledItem) Release() {
+	buf := i.buf
+	if buf == nil {
+		return
+	}
+	i.buf = nil
+	i.Value = nil
+	i.Flags = 0
+	if i.class < 0 {
+		// Too large to be pooled
+		return
+	}
+	bufferPools[i.class].Put(buf)
+}
+
+// newPooledItem returns an item with a buffer of at least n bytes, from the pool of the smallest fitting size class.
+func newPooledItem(n int) *PooledItem {
+	class := max(bits.Len(uint(max(n, 1)-1)), minSizeClassBits) - minSizeClassBits
+	if class >= len(bufferPools) {
+		buf := make([]byte, n)
+		return &PooledItem{buf: &buf, class: -1}
+	}
+	if buf, ok := bufferPools[class].Get().(*[]byte); ok {
+		return &PooledItem{buf: buf, class: class}
+	}
+	buf := make([]byte, 1<<(class+minSizeClassBits))
+	return &PooledItem{buf: &buf, class: class}
+}
+
+// bufferGuard makes sure a caller's buffer is not reused while a canceled request may still be reading into it: after
+// a failed call the buffer is safe to reuse only if abandon returns true.
+type bufferGuard struct {
+	state atomic.Int32
+}
+
+const (
+	bufferIdle int32 = iota
+	bufferInUse
+	bufferAbandoned
+)
+
+// use is called by the reader, returns false if the caller gave up on the request already.
+func (g *bufferGuard) use() bool {
+	return g.state.CompareAndSwap(bufferIdle, bufferInUse) || g.state.Load() == bufferInUse
+}
+
+// abandon returns true if the buffer was never handed to the reader, and never will be.
+func (g *bufferGuard) abandon() bool {
+	return g.state.CompareAndSwap(bufferIdle, bufferAbandoned)
+}
+
+// GetInto appends the value to dst and returns the extended slice, so that reads don't allocate when dst has enough
+// capacity. Returns nil on miss, like Get, and fails on server errors. Batching and hedging don't apply. The value is
+// returned as stored, compressed and chunked values are not decoded and their flags keep the reserved bits.
+// If the call fails while the response is being read (e.g. the context is done), the spare capacity of dst may still be
+// written to, so dst must not be reused then.
+func (c *Client) GetInto(ctx context.Context, key string, dst []byte) ([]byte, uint16, error) {
+	key, err := c.key(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	var guard bufferGuard
+	var grown []byte
+	getMsg := mmc.NewGet(key)
+	getMsg.Alloc = func(n int) []byte {
+		if !guard.use() {
+			return make([]byte, n)
+		}
+		grown = slices.Grow(dst, n)[:len(dst)+n]
+		return grown[len(dst):]
+	}
+	err = c.call(ctx, OpGet, key, getMsg)
+	if err != nil {
+		guard.abandon()
+		return nil, 0, err
+	}
+	if errors.Is(getMsg.Error, mmc.ErrMiss) {
+		return nil, 0, nil
+	}
+	if getMsg.Error != nil {
+		return nil, 0, fmt.Errorf("get %s: %w", key, getMsg.Error)
+	}
+	return grown, getMsg.Flags, nil
+}
+
+// GetPooled returns the value in a pooled buffer, which must be released with PooledItem.Release once not used anymore.
+// Returns nil on miss, like Get, and fails on server errors. Batching and hedging don't apply. The value is returned as
+// stored, like with GetInto.
+func (c *Client) GetPooled(ctx context.Context, key string) (*PooledItem, error) {
+	key, err := c.key(key)
+	if err != nil {
+		return nil, err
+	}
+	var guard bufferGuard
+	var item *PooledItem
+	getMsg := mmc.NewGet(key)
+	getMsg.Alloc = func(n int) []byte {
+		if !guard.use() {
+			return make([]byte, n)
+		}
+		if item != nil {
+			// Allocated by a previous attempt, which has completed
+			item.Release()
+		}
+		item = newPooledItem(n)
+		return (*item.buf)[:n]
+	}
+	err = c.call(ctx, OpGet, key, getMsg)
+	if err != nil {
+		// If the request may still be reading into the buffer, it's left to the garbage collector instead
+		guard.abandon()
+		return nil, err
+	}
+	if getMsg.Error != nil {
+		if item != nil {
+			item.Release()
+		}
+		if errors.Is(getMsg.Error, mmc.ErrMiss) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get %s: %w", key, getMsg.Error)
+	}
+	item.Value = getMsg.Value
+	item.Flags = getMsg.Flags
+	return item, nil
+}
@@ -0,0 +1,134 @@
+package memcached_go
+
+import (
+	"bytes"
+	"context"
+	"memcached-go/mmc"
+	"memcached-go/testutil"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type PoolSuite struct {
+	testutil.BaseSuite
+}
+
+func TestPoolSuite(t *testing.T) {
+	suite.Run(t, new(PoolSuite))
+}
+
+func (s *PoolSuite) TestGetInto() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	fake.Put("foo", 3, []byte("bar"))
+
+	// Appended to dst, without allocating when there is enough capacity
+	dst := make([]byte, 0, 64)
+	dst = append(dst, "prefix:"...)
+	val, flags, err := cli.GetInto(ctx, "foo", dst)
+	s.Require().NoError(err)
+	s.Equal("prefix:bar", string(val))
+	s.Equal(uint16(3), flags)
+	s.Same(&dst[:1][0], &val[0])
+
+	// Grown when there isn't
+	val, _, err = cli.GetInto(ctx, "foo", nil)
+	s.Require().NoError(err)
+	s.Equal("bar", string(val))
+
+	val, _, err = cli.GetInto(ctx, "missing", dst)
+	s.Require().NoError(err)
+	s.Nil(val)
+}
+
+func (s *PoolSuite) TestGetPooled() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	fake.Put("foo", 3, []byte("bar"))
+	large := bytes.Repeat([]byte("x"), 2<<20)
+	fake.Put("large", 0, large)
+
+	for i := 0; i < 3; i++ {
+		item, err := cli.GetPooled(ctx, "foo")
+		s.Require().NoError(err)
+		s.Equal("bar", string(item.Value))
+		s.Equal(uint16(3), item.Flags)
+		s.Equal(64, cap(*item.buf))
+		item.Release()
+		s.Nil(item.Value)
+	}
+
+	// Larger than the largest size class, not pooled
+	item, err := cli.GetPooled(ctx, "large")
+	s.Require().NoError(err)
+	s.Equal(large, item.Value)
+	s.Equal(-1, item.class)
+	item.Release()
+
+	item, err = cli.GetPooled(ctx, "missing")
+	s.Require().NoError(err)
+	s.Nil(item)
+}
+
+func (s *PoolSuite) TestSizeClasses() {
+	s.Equal(0, newPooledItem(0).class)
+	s.Equal(0, newPooledItem(64).class)
+	s.Equal(1, newPooledItem(65).class)
+	s.Equal(len(bufferPools)-1, newPooledItem(1<<20).class)
+	s.Equal(-1, newPooledItem(1<<20+1).class)
+}
+
+func (s *PoolSuite) TestDoubleRelease() {
+	item := newPooledItem(100)
+	item.Release()
+	// The buffer is in use again, releasing the stale item must not put it back
+	reused := newPooledItem(100)
+	item.Release()
+
+	other := newPooledItem(100)
+	s.NotSame(&(*reused.buf)[0], &(*other.buf)[0])
+}
+
+func (s *PoolSuite) TestServerError() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	fake.Put("foo", 0, []byte("bar"))
+
+	fake.FailCommands(1)
+	item, err := cli.GetPooled(ctx, "foo")
+	s.ErrorIs(err, mmc.ErrServerError)
+	s.Nil(item)
+
+	fake.FailCommands(1)
+	val, _, err := cli.GetInto(ctx, "foo", nil)
+	s.ErrorIs(err, mmc.ErrServerError)
+	s.Nil(val)
+
+	// The connection is still in sync
+	item, err = cli.GetPooled(ctx, "foo")
+	s.Require().NoError(err)
+	s.Equal("bar", string(item.Value))
+	item.Release()
+}
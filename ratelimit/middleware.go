@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// KeyFunc identifies the caller of a request, requests with an empty identity are not limited.
+type KeyFunc func(r *http.Request) string
+
+// RemoteIP identifies callers by their IP address. Behind a proxy, identify them by a header set by the proxy instead.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Middleware limits requests by the identity of their callers, responding with 429 Too Many Requests once the limit
+// is reached. Responses carry the X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset (unix seconds)
+// headers. If the limiter fails, e.g. memcached is down, requests are allowed, so that the limiter isn't a single
+// point of failure.
+func Middleware(l Limiter, key KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := key(r)
+			if id == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			res, err := l.Allow(r.Context(), id)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("X-RateLimit-Reset", strconv.FormatInt(res.ResetAt.Unix(), 10))
+			if !res.Allowed {
+				// In whole seconds, rounded up
+				retryAfter := max((time.Until(res.ResetAt)+time.Second-1)/time.Second, 1)
+				h.Set("Retry-After", strconv.Itoa(int(retryAfter)))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
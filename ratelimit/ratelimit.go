@@ -0,0 +1,170 @@
+// Package ratelimit provides rate limiters shared by many processes, counting requests in memcached.
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"memcached-go"
+	"memcached-go/mmc"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Result is the outcome of a request to a limiter.
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining requests in the current window, zero if not allowed
+	Remaining int
+	// ResetAt is when the current window ends
+	ResetAt time.Time
+}
+
+// Limiter limits the rate of requests by caller identity, e.g. a user id or an IP address.
+type Limiter interface {
+	Allow(ctx context.Context, id string) (Result, error)
+}
+
+// window counts the requests of a caller in fixed windows of time, stored under "ratelimit:<name>:<id>:<window>".
+// The id is hashed, as caller identities may be long or contain characters not allowed in keys.
+type window struct {
+	cli    *memcached_go.Client
+	name   string
+	limit  int
+	length time.Duration
+	now    func() time.Time
+}
+
+func newWindow(cli *memcached_go.Client, name string, limit int, length time.Duration) (window, error) {
+	w := window{cli: cli, name: name, limit: limit, length: length, now: time.Now}
+	if length <= 0 {
+		return window{}, fmt.Errorf("rate limiter %s: window must be positive, got %s", name, length)
+	}
+	if limit < 0 {
+		return window{}, fmt.Errorf("rate limiter %s: limit must not be negative, got %d", name, limit)
+	}
+	if err := mmc.ValidateKey(w.key("", math.MinInt64)); err != nil {
+		return window{}, fmt.Errorf("rate limiter %s: %w", name, err)
+	}
+	return w, nil
+}
+
+// current returns the index of the current window and its end.
+func (w window) current() (int64, time.Time) {
+	idx := w.now().UnixNano() / int64(w.length)
+	return idx, time.Unix(0, (idx+1)*int64(w.length))
+}
+
+func (w window) key(id string, idx int64) string {
+	sum := sha256.Sum256([]byte(id))
+	return w.name + ":" + hex.EncodeToString(sum[:16]) + ":" + strconv.FormatInt(idx, 10)
+}
+
+// incr counts a request in the window, the counter expires once it's no longer needed. Memcached expires values with
+// a precision of seconds, hence the extra second.
+func (w window) incr(ctx context.Context, key string, ttl time.Duration) (uint64, error) {
+	for {
+		count, err := w.cli.Incr(ctx, key, 1)
+		if !errors.Is(err, mmc.ErrNotFound) {
+			return count, err
+		}
+		err = w.cli.Add(ctx, key, 0, []byte("1"), ttl+time.Second)
+		if !errors.Is(err, mmc.ErrNotStored) {
+			return 1, err
+		}
+		// Created concurrently
+	}
+}
+
+// FixedWindow allows up to limit requests per window. It's cheap, a single incr for most requests, but bursts of up
+// to twice the limit are possible across the boundary of two windows.
+type FixedWindow struct {
+	window
+}
+
+// NewFixedWindow fails if the window isn't positive, the limit is negative, or the name is too long or not valid in
+// keys. A limit of zero denies all requests, without counting them.
+func NewFixedWindow(cli *memcached_go.Client, name string, limit int, window time.Duration) (*FixedWindow, error) {
+	w, err := newWindow(cli, "ratelimit:"+name, limit, window)
+	if err != nil {
+		return nil, err
+	}
+	return &FixedWindow{window: w}, nil
+}
+
+func (l *FixedWindow) Allow(ctx context.Context, id string) (Result, error) {
+	idx, resetAt := l.current()
+	if l.limit == 0 {
+		return Result{ResetAt: resetAt}, nil
+	}
+	count, err := l.incr(ctx, l.key(id, idx), l.length)
+	if err != nil {
+		return Result{}, fmt.Errorf("count %s: %w", id, err)
+	}
+	remaining := max(l.limit-int(count), 0)
+	return Result{Allowed: count <= uint64(l.limit), Limit: l.limit, Remaining: remaining, ResetAt: resetAt}, nil
+}
+
+// SlidingWindow approximates a window sliding with the current time, by weighting the count of the previous fixed
+// window by how much of it the sliding window still covers. It smooths out the bursts of FixedWindow, at the cost of
+// an extra get. Denied requests are not counted.
+type SlidingWindow struct {
+	window
+}
+
+// NewSlidingWindow fails like NewFixedWindow.
+func NewSlidingWindow(cli *memcached_go.Client, name string, limit int, window time.Duration) (*SlidingWindow, error) {
+	w, err := newWindow(cli, "ratelimit:"+name, limit, window)
+	if err != nil {
+		return nil, err
+	}
+	return &SlidingWindow{window: w}, nil
+}
+
+func (l *SlidingWindow) Allow(ctx context.Context, id string) (Result, error) {
+	idx, resetAt := l.current()
+	if l.limit == 0 {
+		return Result{ResetAt: resetAt}, nil
+	}
+	key := l.key(id, idx)
+	// The counter is needed for the whole next window too, as the previous one
+	count, err := l.incr(ctx, key, 2*l.length)
+	if err != nil {
+		return Result{}, fmt.Errorf("count %s: %w", id, err)
+	}
+	prev, err := l.count(ctx, l.key(id, idx-1))
+	if err != nil {
+		return Result{}, fmt.Errorf("count %s: %w", id, err)
+	}
+
+	covered := float64(resetAt.Sub(l.now())) / float64(l.length)
+	estimate := int(float64(prev)*covered) + int(count)
+	if estimate > l.limit {
+		// Denied requests would otherwise count against the caller in the next window too
+		_, err = l.cli.Decr(ctx, key, 1)
+		if err != nil && !errors.Is(err, mmc.ErrNotFound) {
+			return Result{}, fmt.Errorf("uncount %s: %w", id, err)
+		}
+		return Result{Limit: l.limit, ResetAt: resetAt}, nil
+	}
+	return Result{Allowed: true, Limit: l.limit, Remaining: l.limit - estimate, ResetAt: resetAt}, nil
+}
+
+// count returns the count of a window, zero if it's missing.
+func (l *SlidingWindow) count(ctx context.Context, key string) (uint64, error) {
+	val, err := l.cli.GetV(ctx, key)
+	if err != nil || val == nil {
+		return 0, err
+	}
+	// Decremented counters may be padded with spaces
+	count, err := strconv.ParseUint(strings.TrimSpace(string(val)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid count %q of %s: %w", string(val), key, err)
+	}
+	return count, nil
+}
@@ -0,0 +1,188 @@
+package ratelimit
+
+import (
+	"context"
+	"memcached-go"
+	"memcached-go/mmc"
+	"memcached-go/testutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RateLimitSuite struct {
+	testutil.BaseSuite
+	fake *testutil.FakeMemcached
+	cli  *memcached_go.Client
+	now  time.Time
+}
+
+func TestRateLimitSuite(t *testing.T) {
+	suite.Run(t, new(RateLimitSuite))
+}
+
+func (s *RateLimitSuite) SetupTest() {
+	var err error
+	s.fake, err = testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	s.cli, err = memcached_go.NewClient(s.fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	// At the start of a window
+	s.now = time.Now().Truncate(time.Hour).Add(time.Hour)
+}
+
+func (s *RateLimitSuite) TearDownTest() {
+	s.cli.Close()
+	s.fake.Close()
+}
+
+func (s *RateLimitSuite) clock() time.Time {
+	return s.now
+}
+
+func (s *RateLimitSuite) TestFixedWindow() {
+	ctx := context.Background()
+	l, err := NewFixedWindow(s.cli, "api", 3, time.Minute)
+	s.Require().NoError(err)
+	l.now = s.clock
+
+	for i := range 3 {
+		res, err := l.Allow(ctx, "user-1")
+		s.Require().NoError(err)
+		s.Equal(Result{Allowed: true, Limit: 3, Remaining: 2 - i, ResetAt: s.now.Add(time.Minute)}, res)
+	}
+	res, err := l.Allow(ctx, "user-1")
+	s.Require().NoError(err)
+	s.False(res.Allowed)
+	s.Zero(res.Remaining)
+
+	// Callers are limited separately
+	res, err = l.Allow(ctx, "user-2")
+	s.Require().NoError(err)
+	s.True(res.Allowed)
+
+	s.now = s.now.Add(time.Minute)
+	res, err = l.Allow(ctx, "user-1")
+	s.Require().NoError(err)
+	s.True(res.Allowed)
+	s.Equal(2, res.Remaining)
+}
+
+func (s *RateLimitSuite) TestSlidingWindow() {
+	ctx := context.Background()
+	l, err := NewSlidingWindow(s.cli, "api", 10, time.Minute)
+	s.Require().NoError(err)
+	l.now = s.clock
+
+	for range 10 {
+		res, err := l.Allow(ctx, "user-1")
+		s.Require().NoError(err)
+		s.True(res.Allowed)
+	}
+	res, err := l.Allow(ctx, "user-1")
+	s.Require().NoError(err)
+	s.False(res.Allowed)
+
+	// Half of the previous window is still covered
+	s.now = s.now.Add(90 * time.Second)
+	for i := range 5 {
+		res, err = l.Allow(ctx, "user-1")
+		s.Require().NoError(err)
+		s.True(res.Allowed)
+		s.Equal(4-i, res.Remaining)
+	}
+	for range 3 {
+		res, err = l.Allow(ctx, "user-1")
+		s.Require().NoError(err)
+		s.False(res.Allowed)
+	}
+
+	// Denied requests were not counted
+	s.now = s.now.Add(time.Minute)
+	res, err = l.Allow(ctx, "user-1")
+	s.Require().NoError(err)
+	s.True(res.Allowed)
+	s.Equal(7, res.Remaining)
+}
+
+func (s *RateLimitSuite) TestMiddleware() {
+	l, err := NewFixedWindow(s.cli, "api", 1, time.Minute)
+	s.Require().NoError(err)
+	l.now = s.clock
+	handler := Middleware(l, RemoteIP)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("10.0.0.1:1234")
+	s.Equal(http.StatusNoContent, rec.Code)
+	s.Equal("1", rec.Header().Get("X-RateLimit-Limit"))
+	s.Equal("0", rec.Header().Get("X-RateLimit-Remaining"))
+
+	rec = serve("10.0.0.1:5678")
+	s.Equal(http.StatusTooManyRequests, rec.Code)
+	s.NotEmpty(rec.Header().Get("Retry-After"))
+	s.Equal(http.StatusNoContent, serve("10.0.0.2:1234").Code)
+
+	// Allowed while memcached is down
+	s.fake.Close()
+	s.Equal(http.StatusNoContent, serve("10.0.0.1:1234").Code)
+}
+
+func (s *RateLimitSuite) TestCallerIDs() {
+	ctx := context.Background()
+	l, err := NewFixedWindow(s.cli, "api", 1, time.Minute)
+	s.Require().NoError(err)
+	l.now = s.clock
+
+	// Not valid in keys as is, but limited all the same
+	for _, id := range []string{"user 1", "user\r\n1", strings.Repeat("u", 300)} {
+		res, err := l.Allow(ctx, id)
+		s.Require().NoError(err)
+		s.True(res.Allowed, id)
+		res, err = l.Allow(ctx, id)
+		s.Require().NoError(err)
+		s.False(res.Allowed, id)
+	}
+}
+
+func (s *RateLimitSuite) TestInvalidConfig() {
+	_, err := NewFixedWindow(s.cli, "api", 1, 0)
+	s.Error(err)
+	_, err = NewSlidingWindow(s.cli, "api", 1, -time.Second)
+	s.Error(err)
+	_, err = NewFixedWindow(s.cli, "my api", 1, time.Minute)
+	s.ErrorIs(err, mmc.ErrInvalidKey)
+	_, err = NewFixedWindow(s.cli, "api", -1, time.Minute)
+	s.Error(err)
+	_, err = NewSlidingWindow(s.cli, "api", -1, time.Minute)
+	s.Error(err)
+}
+
+func (s *RateLimitSuite) TestZeroLimit() {
+	ctx := context.Background()
+	fixed, err := NewFixedWindow(s.cli, "fixed", 0, time.Minute)
+	s.Require().NoError(err)
+	sliding, err := NewSlidingWindow(s.cli, "sliding", 0, time.Minute)
+	s.Require().NoError(err)
+
+	for _, l := range []Limiter{fixed, sliding} {
+		res, err := l.Allow(ctx, "user")
+		s.Require().NoError(err)
+		s.False(res.Allowed)
+		s.Equal(0, res.Remaining)
+	}
+	// Not counted
+	s.Equal(0, s.fake.Commands())
+}
@@ -0,0 +1,143 @@
+package memcached_go
+
+import (
+	"context"
+	"memcached-go/gonet"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Command names, as used by retry policies and reported to hooks.
+const (
+	OpGet    = "get"
+	OpGets   = "gets"
+	OpTouch  = "touch"
+	OpDelete = "delete"
+	OpSet    = "set"
+	OpAdd    = "add"
+	OpCas    = "cas"
+	OpIncr   = "incr"
+	OpDecr   = "decr"
+)
+
+// DefaultRetryOps are the idempotent commands, which are safe to send again when the outcome of the previous attempt
+// is unknown. Add, cas, incr and decr are not, e.g. a retried add may fail because the first attempt succeeded.
+var DefaultRetryOps = []string{OpGet, OpGets, OpTouch, OpDelete, OpSet}
+
+// RetryPolicy retries commands failed due to connection errors (see gonet.IsConnectionError). Protocol-level outcomes,
+// such as a miss or NOT_STORED, are never retried.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt, values below 2 disable retries
+	MaxAttempts int
+	// Backoff between attempts, no delay if nil
+	Backoff gonet.Backoff
+	// Ops to retry, DefaultRetryOps if empty
+	Ops []string
+	// Budget is optional, and can be shared between clients
+	Budget *RetryBudget
+}
+
+// WithRetry enables retries of failed commands.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		if len(policy.Ops) == 0 {
+			policy.Ops = DefaultRetryOps
+		}
+		c.retry = &policy
+	}
+}
+
+// RetryBudget limits the share of retries across many calls, to avoid retry storms while the server is down.
+// Modeled after gRPC retry throttling: each failed attempt takes a token, each successful one gives back ratio tokens,
+// and retries are allowed only while more than half of the tokens are available.
+type RetryBudget struct {
+	maxTokens float64
+	ratio     float64
+
+	lock   sync.Mutex
+	tokens float64
+}
+
+func NewRetryBudget(maxTokens int, ratio float64) *RetryBudget {
+	return &RetryBudget{
+		maxTokens: float64(maxTokens),
+		ratio:     ratio,
+		tokens:    float64(maxTokens),
+	}
+}
+
+func (b *RetryBudget) onSuccess() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.tokens = min(b.tokens+b.ratio, b.maxTokens)
+}
+
+// onFailure records a failed attempt and reports whether a retry is allowed.
+func (b *RetryBudget) onFailure() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.tokens = max(b.tokens-1, 0)
+	return b.tokens > b.maxTokens/2
+}
+
+// call executes the message, retrying according to the retry policy, and reports every attempt to hooks.
+func (c *Client) call(ctx context.Context, op, key string, msg gonet.Message) error {
+	return c.attempt(ctx, op, key, func() error {
+		return c.cli.Call(ctx, msg)
+	})
+}
+
+// attempt runs f until it succeeds or the retry policy gives up.
+func (c *Client) attempt(ctx context.Context, op, key string, f func() error) error {
+	var delay time.Duration
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		err := f()
+		c.observe(ctx, Attempt{Op: op, Key: key, Number: attempt, Duration: time.Since(start), Err: err})
+
+		if !c.shouldRetry(op, attempt, err) {
+			return err
+		}
+
+		if c.retry.Backoff != nil {
+			delay = c.retry.Backoff.Next(attempt, delay)
+			if err := sleep(ctx, delay); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (c *Client) shouldRetry(op string, attempt int, err error) bool {
+	if c.retry == nil {
+		return false
+	}
+	if err == nil {
+		if c.retry.Budget != nil {
+			c.retry.Budget.onSuccess()
+		}
+		return false
+	}
+	if !gonet.IsConnectionError(err) {
+		return false
+	}
+	if attempt >= c.retry.MaxAttempts || !slices.Contains(c.retry.Ops, op) {
+		return false
+	}
+	return c.retry.Budget == nil || c.retry.Budget.onFailure()
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
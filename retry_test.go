@@ -0,0 +1,141 @@
+package memcached_go
+
+import (
+	"context"
+	"memcached-go/gonet"
+	"memcached-go/testutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RetrySuite struct {
+	testutil.BaseSuite
+
+	fake     *testutil.FakeMemcached
+	lock     sync.Mutex
+	attempts []Attempt
+}
+
+func TestRetrySuite(t *testing.T) {
+	suite.Run(t, new(RetrySuite))
+}
+
+func (s *RetrySuite) SetupTest() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	s.fake = fake
+	s.attempts = nil
+}
+
+func (s *RetrySuite) TearDownTest() {
+	s.fake.Close()
+}
+
+func (s *RetrySuite) newClient(policy RetryPolicy) *Client {
+	hook := func(_ context.Context, attempt Attempt) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.attempts = append(s.attempts, attempt)
+	}
+	cli, err := NewClient(s.fake.Addr(), 1, 2, WithRetry(policy), WithHook(hook))
+	s.Require().NoError(err)
+	return cli
+}
+
+func (s *RetrySuite) attemptNumbers() []int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var numbers []int
+	for _, a := range s.attempts {
+		numbers = append(numbers, a.Number)
+	}
+	return numbers
+}
+
+func (s *RetrySuite) TestRetryOnConnectionError() {
+	cli := s.newClient(RetryPolicy{MaxAttempts: 3, Backoff: gonet.ConstantBackoff{Delay: time.Millisecond}})
+	defer cli.Close()
+
+	s.fake.Put("foo", 3, []byte("bar"))
+	s.fake.DropConnections(1)
+
+	val, flags, err := cli.Get(context.Background(), "foo")
+	s.Require().NoError(err)
+	s.Equal("bar", string(val))
+	s.Equal(uint16(3), flags)
+	s.Equal([]int{1, 2}, s.attemptNumbers())
+	s.True(gonet.IsConnectionError(s.attempts[0].Err))
+}
+
+func (s *RetrySuite) TestMaxAttempts() {
+	cli := s.newClient(RetryPolicy{MaxAttempts: 2})
+	defer cli.Close()
+
+	s.fake.DropConnections(5)
+
+	err := cli.SetV(context.Background(), "foo", []byte("bar"))
+	s.Require().Error(err)
+	s.True(gonet.IsConnectionError(err))
+	s.Equal([]int{1, 2}, s.attemptNumbers())
+}
+
+func (s *RetrySuite) TestNoRetryOnMiss() {
+	cli := s.newClient(RetryPolicy{MaxAttempts: 3})
+	defer cli.Close()
+
+	val, err := cli.GetV(context.Background(), "missing")
+	s.Require().NoError(err)
+	s.Nil(val)
+	s.Equal([]int{1}, s.attemptNumbers())
+}
+
+func (s *RetrySuite) TestOnlyConfiguredOps() {
+	cli := s.newClient(RetryPolicy{MaxAttempts: 3, Ops: []string{OpGet}})
+	defer cli.Close()
+
+	s.fake.DropConnections(1)
+
+	err := cli.SetV(context.Background(), "foo", []byte("bar"))
+	s.Require().Error(err)
+	s.Equal([]int{1}, s.attemptNumbers())
+}
+
+func (s *RetrySuite) TestBudget() {
+	budget := NewRetryBudget(4, 1)
+	cli := s.newClient(RetryPolicy{MaxAttempts: 5, Budget: budget})
+	defer cli.Close()
+
+	// The budget allows a single retry, going below half of the tokens afterward
+	s.fake.DropConnections(5)
+	_, err := cli.GetV(context.Background(), "foo")
+	s.Require().Error(err)
+	s.Equal([]int{1, 2}, s.attemptNumbers())
+
+	// Successful calls refill the budget
+	s.fake.DropConnections(0)
+	for i := 0; i < 2; i++ {
+		_, err = cli.GetV(context.Background(), "foo")
+		s.Require().NoError(err)
+	}
+	s.fake.DropConnections(1)
+	_, err = cli.GetV(context.Background(), "foo")
+	s.Require().NoError(err)
+}
+
+func (s *RetrySuite) TestBudgetOnlyForRetries() {
+	budget := NewRetryBudget(4, 1)
+	cli := s.newClient(RetryPolicy{MaxAttempts: 2, Ops: []string{OpGet}, Budget: budget})
+	defer cli.Close()
+
+	// Neither a non-retryable op nor the final attempt take tokens
+	s.fake.DropConnections(3)
+	err := cli.SetV(context.Background(), "foo", []byte("bar"))
+	s.Require().Error(err)
+	_, err = cli.GetV(context.Background(), "foo")
+	s.Require().Error(err)
+	s.Equal([]int{1, 1, 2}, s.attemptNumbers())
+	s.Equal(3.0, budget.tokens)
+}
@@ -0,0 +1,104 @@
+package sessions
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type contextKey struct{}
+
+// FromContext returns the session of the request, set by Middleware.
+func FromContext(ctx context.Context) *Session {
+	sess, _ := ctx.Value(contextKey{}).(*Session)
+	return sess
+}
+
+// Middleware loads the session of the request by its cookie, or starts a new one, see FromContext. The session is
+// saved right before the response header is written, and the cookie is set, so handlers only need to modify it. If
+// the session can't be loaded or saved, the response is 500 Internal Server Error. Unmodified sessions are touched,
+// to extend their expiration.
+func (s *Store) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var sess *Session
+		if cookie, err := r.Cookie(s.cookie.Name); err == nil && validID(cookie.Value) {
+			sess, err = s.Load(r.Context(), cookie.Value)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+		}
+		if sess == nil {
+			sess = s.New()
+		}
+
+		sw := &sessionWriter{ResponseWriter: w, store: s, sess: sess, ctx: r.Context()}
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), contextKey{}, sess)))
+		sw.commit()
+	})
+}
+
+// sessionWriter saves the session once the handler starts responding, as the cookie has to be set before the header
+// is written.
+type sessionWriter struct {
+	http.ResponseWriter
+	store *Store
+	sess  *Session
+	ctx   context.Context
+
+	committed bool
+	// The response was replaced with an error, writes of the handler are dropped
+	failed bool
+}
+
+func (sw *sessionWriter) WriteHeader(code int) {
+	sw.commit()
+	if !sw.failed {
+		sw.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (sw *sessionWriter) Write(b []byte) (int, error) {
+	sw.commit()
+	if sw.failed {
+		return len(b), nil
+	}
+	return sw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (sw *sessionWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+func (sw *sessionWriter) commit() {
+	if sw.committed {
+		return
+	}
+	sw.committed = true
+
+	s, sess := sw.store, sw.sess
+	switch {
+	case sess.Modified():
+		if err := s.Save(sw.ctx, sess); err != nil {
+			sw.failed = true
+			http.Error(sw.ResponseWriter, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	case sess.ID != "":
+		// Best effort, failing to extend the session is not worth failing the request
+		_ = s.Touch(sw.ctx, sess.ID)
+	default:
+		// A new session without values is not stored
+		return
+	}
+
+	cookie := s.cookie
+	if sess.destroyed {
+		cookie.MaxAge = -1
+	} else {
+		cookie.Value = sess.ID
+		cookie.MaxAge = int(s.ttl / time.Second)
+	}
+	http.SetCookie(sw.ResponseWriter, &cookie)
+}
@@ -0,0 +1,282 @@
+// Package sessions stores HTTP sessions in memcached. Sessions expire after a period of inactivity, every request
+// extends them with touch, and concurrent updates are merged key by key using CAS.
+package sessions
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"memcached-go"
+	"memcached-go/mmc"
+	"net/http"
+	"time"
+)
+
+// Values of a session, they must be supported by the codec, e.g. registered with gob.Register for a GobCodec.
+type Values map[string]any
+
+// maxSaveAttempts limits retries of saves conflicting with concurrent updates
+const maxSaveAttempts = 10
+
+var (
+	ErrInvalidID = errors.New("invalid session id")
+	ErrConflict  = errors.New("session updated concurrently")
+)
+
+// Option customizes the Store created with NewStore.
+type Option func(s *Store)
+
+// WithCodec sets the serializer of session values, JSONCodec by default.
+func WithCodec(codec memcached_go.Codec[Values]) Option {
+	return func(s *Store) {
+		s.codec = codec
+	}
+}
+
+// WithCookie sets the attributes of the session cookie, the value and expiration are set by the store.
+func WithCookie(cookie http.Cookie) Option {
+	return func(s *Store) {
+		s.cookie = cookie
+	}
+}
+
+// Store keeps sessions under "session:<id>".
+type Store struct {
+	cli    *memcached_go.Client
+	ttl    time.Duration
+	codec  memcached_go.Codec[Values]
+	cookie http.Cookie
+}
+
+// NewStore creates a store of sessions, which expire after ttl of inactivity. By default the cookie is named
+// "session", it's secure, HTTP only and SameSite=Lax.
+func NewStore(cli *memcached_go.Client, ttl time.Duration, opts ...Option) *Store {
+	s := &Store{
+		cli:   cli,
+		ttl:   ttl,
+		codec: memcached_go.JSONCodec[Values]{},
+		cookie: http.Cookie{
+			Name:     "session",
+			Path:     "/",
+			Secure:   true,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Session is not safe for concurrent use, concurrent requests of the same session have their own copies, see Save.
+type Session struct {
+	// ID is empty until a new session is saved
+	ID string
+
+	values Values
+	// Keys set or deleted since the session was loaded
+	changed map[string]bool
+	// CAS unique of the stored session, zero if it's not stored
+	cas       uint64
+	destroyed bool
+}
+
+// New returns an empty session, which is stored once it's saved.
+func (s *Store) New() *Session {
+	return &Session{values: Values{}}
+}
+
+func (sess *Session) Get(key string) (any, bool) {
+	v, ok := sess.values[key]
+	return v, ok
+}
+
+func (sess *Session) Set(key string, v any) {
+	sess.values[key] = v
+	sess.markChanged(key)
+}
+
+func (sess *Session) Delete(key string) {
+	delete(sess.values, key)
+	sess.markChanged(key)
+}
+
+// Destroy deletes the session once it's saved.
+func (sess *Session) Destroy() {
+	sess.destroyed = true
+}
+
+func (sess *Session) markChanged(key string) {
+	if sess.changed == nil {
+		sess.changed = map[string]bool{}
+	}
+	sess.changed[key] = true
+}
+
+// Modified reports whether the session has changes to save.
+func (sess *Session) Modified() bool {
+	return len(sess.changed) > 0 || sess.destroyed
+}
+
+// Load returns the stored session, or nil if it doesn't exist, e.g. it expired.
+func (s *Store) Load(ctx context.Context, id string) (*Session, error) {
+	if !validID(id) {
+		return nil, ErrInvalidID
+	}
+	values, cas, err := s.load(ctx, id)
+	if err != nil || cas == 0 {
+		return nil, err
+	}
+	return &Session{ID: id, values: values, cas: cas}, nil
+}
+
+// load returns the values with their CAS unique, which is zero if the session doesn't exist.
+func (s *Store) load(ctx context.Context, id string) (Values, uint64, error) {
+	data, flags, cas, err := s.cli.Gets(ctx, key(id))
+	if err != nil || data == nil {
+		return nil, 0, err
+	}
+	if uint8(flags) != s.codec.ID() {
+		return nil, 0, fmt.Errorf("session %s: %w", id, memcached_go.ErrCodecMismatch)
+	}
+	var values Values
+	if err = s.codec.Unmarshal(data, &values); err != nil {
+		return nil, 0, fmt.Errorf("unmarshal session %s: %w", id, err)
+	}
+	if values == nil {
+		values = Values{}
+	}
+	return values, cas, nil
+}
+
+// Save stores the changes of the session. If it was updated concurrently, e.g. by a parallel request, the changed
+// keys are applied over the latest values, so that changes of other keys are not lost. If conflicts persist, Save
+// fails with ErrConflict.
+func (s *Store) Save(ctx context.Context, sess *Session) error {
+	if sess.destroyed {
+		return s.destroy(ctx, sess)
+	}
+	if sess.ID == "" {
+		return s.create(ctx, sess)
+	}
+
+	for range maxSaveAttempts {
+		data, err := s.codec.Marshal(sess.values)
+		if err != nil {
+			return fmt.Errorf("marshal session %s: %w", sess.ID, err)
+		}
+		if sess.cas == 0 {
+			// Expired meanwhile, it's stored again under the same id
+			err = s.cli.Add(ctx, key(sess.ID), uint16(s.codec.ID()), data, s.ttl)
+		} else {
+			err = s.cli.CompareAndSwap(ctx, key(sess.ID), uint16(s.codec.ID()), data, s.ttl, sess.cas)
+		}
+		if err == nil {
+			sess.changed = nil
+			return s.reload(ctx, sess)
+		}
+		if !errors.Is(err, mmc.ErrExists) && !errors.Is(err, mmc.ErrNotFound) && !errors.Is(err, mmc.ErrNotStored) {
+			return fmt.Errorf("save session %s: %w", sess.ID, err)
+		}
+
+		latest, cas, err := s.load(ctx, sess.ID)
+		if err != nil {
+			return err
+		}
+		sess.merge(latest, cas)
+	}
+	return fmt.Errorf("save session %s: %w", sess.ID, ErrConflict)
+}
+
+// create stores a new session under a fresh id.
+func (s *Store) create(ctx context.Context, sess *Session) error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	data, err := s.codec.Marshal(sess.values)
+	if err != nil {
+		return fmt.Errorf("marshal session: %w", err)
+	}
+	if err = s.cli.Add(ctx, key(id), uint16(s.codec.ID()), data, s.ttl); err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+	sess.ID = id
+	sess.changed = nil
+	return s.reload(ctx, sess)
+}
+
+// reload fetches the session just stored, as storage commands don't return the CAS unique. It may include changes
+// of concurrent updates made meanwhile.
+func (s *Store) reload(ctx context.Context, sess *Session) error {
+	values, cas, err := s.load(ctx, sess.ID)
+	if err != nil {
+		return err
+	}
+	if cas != 0 {
+		sess.values = values
+	}
+	sess.cas = cas
+	return nil
+}
+
+func (s *Store) destroy(ctx context.Context, sess *Session) error {
+	if sess.ID == "" {
+		return nil
+	}
+	if err := s.cli.Delete(ctx, key(sess.ID)); err != nil {
+		return fmt.Errorf("destroy session %s: %w", sess.ID, err)
+	}
+	sess.cas = 0
+	return nil
+}
+
+// merge applies the changes of the session over the latest stored values.
+func (sess *Session) merge(latest Values, cas uint64) {
+	values := maps.Clone(latest)
+	if values == nil {
+		values = Values{}
+	}
+	for k := range sess.changed {
+		if v, ok := sess.values[k]; ok {
+			values[k] = v
+		} else {
+			delete(values, k)
+		}
+	}
+	sess.values = values
+	sess.cas = cas
+}
+
+// Touch extends the expiration of the session, it fails with mmc.ErrNotFound if the session doesn't exist.
+func (s *Store) Touch(ctx context.Context, id string) error {
+	if !validID(id) {
+		return ErrInvalidID
+	}
+	return s.cli.Touch(ctx, key(id), s.ttl)
+}
+
+func key(id string) string {
+	return "session:" + id
+}
+
+// idBytes of randomness, as recommended by OWASP for session ids (at least 64 bits of entropy)
+const idBytes = 32
+
+func newID() (string, error) {
+	random := make([]byte, idBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("session id: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// validID checks ids coming from cookies, before they are used in keys.
+func validID(id string) bool {
+	decoded, err := base64.RawURLEncoding.DecodeString(id)
+	return err == nil && len(decoded) == idBytes
+}
@@ -0,0 +1,174 @@
+package sessions
+
+import (
+	"context"
+	"io"
+	"memcached-go"
+	"memcached-go/mmc"
+	"memcached-go/testutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SessionsSuite struct {
+	testutil.BaseSuite
+	fake *testutil.FakeMemcached
+	cli  *memcached_go.Client
+}
+
+func TestSessionsSuite(t *testing.T) {
+	suite.Run(t, new(SessionsSuite))
+}
+
+func (s *SessionsSuite) SetupTest() {
+	var err error
+	s.fake, err = testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	s.cli, err = memcached_go.NewClient(s.fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+}
+
+func (s *SessionsSuite) TearDownTest() {
+	s.cli.Close()
+	s.fake.Close()
+}
+
+func (s *SessionsSuite) TestSaveLoad() {
+	ctx := context.Background()
+	store := NewStore(s.cli, time.Hour)
+
+	sess := store.New()
+	sess.Set("user", "alice")
+	s.Require().NoError(store.Save(ctx, sess))
+	s.Require().NotEmpty(sess.ID)
+
+	loaded, err := store.Load(ctx, sess.ID)
+	s.Require().NoError(err)
+	s.Require().NotNil(loaded)
+	user, ok := loaded.Get("user")
+	s.True(ok)
+	s.Equal("alice", user)
+	s.False(loaded.Modified())
+
+	loaded.Delete("user")
+	s.Require().NoError(store.Save(ctx, loaded))
+	loaded, err = store.Load(ctx, sess.ID)
+	s.Require().NoError(err)
+	_, ok = loaded.Get("user")
+	s.False(ok)
+
+	s.Require().NoError(store.Touch(ctx, sess.ID))
+	loaded.Destroy()
+	s.Require().NoError(store.Save(ctx, loaded))
+	loaded, err = store.Load(ctx, sess.ID)
+	s.Require().NoError(err)
+	s.Nil(loaded)
+	s.ErrorIs(store.Touch(ctx, sess.ID), mmc.ErrNotFound)
+
+	_, err = store.Load(ctx, "foo bar")
+	s.ErrorIs(err, ErrInvalidID)
+}
+
+func (s *SessionsSuite) TestConcurrentUpdates() {
+	ctx := context.Background()
+	store := NewStore(s.cli, time.Hour)
+
+	sess := store.New()
+	sess.Set("cart", "empty")
+	s.Require().NoError(store.Save(ctx, sess))
+
+	// Parallel requests of the same session
+	first, err := store.Load(ctx, sess.ID)
+	s.Require().NoError(err)
+	second, err := store.Load(ctx, sess.ID)
+	s.Require().NoError(err)
+	first.Set("theme", "dark")
+	second.Set("cart", "full")
+	s.Require().NoError(store.Save(ctx, first))
+	s.Require().NoError(store.Save(ctx, second))
+
+	loaded, err := store.Load(ctx, sess.ID)
+	s.Require().NoError(err)
+	theme, _ := loaded.Get("theme")
+	cart, _ := loaded.Get("cart")
+	s.Equal("dark", theme)
+	s.Equal("full", cart)
+
+	// Stored again if it expired meanwhile
+	s.Require().NoError(s.cli.Delete(ctx, "session:"+sess.ID))
+	first.Set("theme", "light")
+	s.Require().NoError(store.Save(ctx, first))
+	loaded, err = store.Load(ctx, sess.ID)
+	s.Require().NoError(err)
+	theme, _ = loaded.Get("theme")
+	s.Equal("light", theme)
+}
+
+func (s *SessionsSuite) TestCodec() {
+	ctx := context.Background()
+	gobStore := NewStore(s.cli, time.Hour, WithCodec(memcached_go.GobCodec[Values]{}))
+
+	sess := gobStore.New()
+	sess.Set("visits", 3)
+	s.Require().NoError(gobStore.Save(ctx, sess))
+	loaded, err := gobStore.Load(ctx, sess.ID)
+	s.Require().NoError(err)
+	visits, _ := loaded.Get("visits")
+	s.Equal(3, visits)
+
+	_, err = NewStore(s.cli, time.Hour).Load(ctx, sess.ID)
+	s.ErrorIs(err, memcached_go.ErrCodecMismatch)
+}
+
+func (s *SessionsSuite) TestMiddleware() {
+	store := NewStore(s.cli, time.Hour, WithCookie(http.Cookie{Name: "sid", Path: "/", HttpOnly: true}))
+	handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess := FromContext(r.Context())
+		switch r.URL.Path {
+		case "/login":
+			sess.Set("user", "alice")
+		case "/logout":
+			sess.Destroy()
+		}
+		user, _ := sess.Get("user")
+		_, _ = io.WriteString(w, user.(string))
+	}))
+
+	serve := func(path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/login", nil)
+	s.Equal("alice", rec.Body.String())
+	cookies := rec.Result().Cookies()
+	s.Require().Len(cookies, 1)
+	cookie := cookies[0]
+	s.Equal("sid", cookie.Name)
+	s.Equal(3600, cookie.MaxAge)
+
+	// Touched and the cookie extended
+	rec = serve("/", cookie)
+	s.Equal("alice", rec.Body.String())
+	s.Len(rec.Result().Cookies(), 1)
+
+	rec = serve("/logout", cookie)
+	s.Require().Len(rec.Result().Cookies(), 1)
+	s.Negative(rec.Result().Cookies()[0].MaxAge)
+	loaded, err := store.Load(context.Background(), cookie.Value)
+	s.Require().NoError(err)
+	s.Nil(loaded)
+
+	s.fake.Close()
+	rec = serve("/", cookie)
+	s.Equal(http.StatusInternalServerError, rec.Code)
+}
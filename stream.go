@@ -0,0 +1,110 @@
+package memcached_go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"memcached-go/mmc"
+	"sync/atomic"
+	"time"
+)
+
+// Streamed values are reported to hooks with these ops, they are not retried by default, as the caller's reader or
+// writer can't be rewound.
+const (
+	OpSetStream = "set_stream"
+	OpGetStream = "get_stream"
+)
+
+var errStreamDone = errors.New("call returned before the value was streamed")
+
+// streamGuard stops passing data from or to the caller once the call has returned, as the connection may still be
+// processing the request, e.g. when the context is done. It doesn't wait for a read or write in progress, which may
+// block, the connection is closed instead, see mmc.SetStream.AbortOnCancel.
+type streamGuard struct {
+	done atomic.Bool
+}
+
+func (g *streamGuard) close() {
+	g.done.Store(true)
+}
+
+type guardedReader struct {
+	guard *streamGuard
+	r     io.Reader
+}
+
+func (r guardedReader) Read(p []byte) (int, error) {
+	if r.guard.done.Load() {
+		return 0, errStreamDone
+	}
+	return r.r.Read(p)
+}
+
+type guardedWriter struct {
+	guard *streamGuard
+	w     io.Writer
+}
+
+func (w guardedWriter) Write(p []byte) (int, error) {
+	if w.guard.done.Load() {
+		return 0, errStreamDone
+	}
+	return w.w.Write(p)
+}
+
+// SetFrom stores a value of size bytes read from r, which is streamed to the server without holding it in memory.
+// If r fails, or has fewer bytes, the server rejects the value and the error of r is returned. If the context is done
+// first, the connection is closed, and a read of r in progress may still complete after SetFrom returned.
+func (c *Client) SetFrom(
+	ctx context.Context, key string, r io.Reader, size int64, flags uint16, ttl time.Duration,
+) error {
+	key, err := c.key(key)
+	if err != nil {
+		return err
+	}
+	if size < 0 {
+		return fmt.Errorf("set %s: negative size %d", key, size)
+	}
+	guard := &streamGuard{}
+	defer guard.close()
+
+	setMsg := mmc.NewSetStream(key, flags, guardedReader{guard: guard, r: r}, size, ttl)
+	defer c.invalidateNear(key)
+	err = c.call(ctx, OpSetStream, key, setMsg)
+	if err != nil {
+		return err
+	}
+	if setMsg.Error != nil {
+		return setMsg.Error
+	}
+	return nil
+}
+
+// GetTo writes the value to w, as it's streamed from the server without holding it in memory, and returns its flags.
+// Returns false on miss. If w fails, the rest of the value is discarded and the error of w is returned.
+// The value is written as stored, compressed and chunked values are not decoded and their flags keep the reserved bits.
+// If the context is done first, the connection is closed, and a write to w in progress may still complete after GetTo
+// returned.
+func (c *Client) GetTo(ctx context.Context, key string, w io.Writer) (uint16, bool, error) {
+	key, err := c.key(key)
+	if err != nil {
+		return 0, false, err
+	}
+	guard := &streamGuard{}
+	defer guard.close()
+
+	getMsg := mmc.NewGetStream(key, guardedWriter{guard: guard, w: w})
+	err = c.call(ctx, OpGetStream, key, getMsg)
+	if err != nil {
+		return 0, false, err
+	}
+	if getMsg.Error != nil {
+		if errors.Is(getMsg.Error, mmc.ErrMiss) {
+			return 0, false, nil
+		}
+		return 0, false, getMsg.Error
+	}
+	return getMsg.Flags, true, nil
+}
@@ -0,0 +1,137 @@
+package memcached_go
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"memcached-go/testutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StreamSuite struct {
+	testutil.BaseSuite
+}
+
+func TestStreamSuite(t *testing.T) {
+	suite.Run(t, new(StreamSuite))
+}
+
+// failingWriter fails after accepting limit bytes
+type failingWriter struct {
+	limit int
+	buf   bytes.Buffer
+}
+
+var errWriter = errors.New("writer failed")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.buf.Len()+len(p) > w.limit {
+		return 0, errWriter
+	}
+	return w.buf.Write(p)
+}
+
+func (s *StreamSuite) TestStreaming() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	large := bytes.Repeat([]byte("0123456789"), 300_000)
+	s.Require().NoError(cli.SetFrom(ctx, "large", bytes.NewReader(large), int64(len(large)), 7, 0))
+	stored, flags, ok := fake.Get("large")
+	s.Require().True(ok)
+	s.Equal(large, stored)
+	s.Equal(uint32(7), flags)
+
+	var buf bytes.Buffer
+	readFlags, found, err := cli.GetTo(ctx, "large", &buf)
+	s.Require().NoError(err)
+	s.True(found)
+	s.Equal(uint16(7), readFlags)
+	s.Equal(large, buf.Bytes())
+
+	_, found, err = cli.GetTo(ctx, "missing", &buf)
+	s.Require().NoError(err)
+	s.False(found)
+}
+
+func (s *StreamSuite) TestFailures() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	// Single connection, to check it's still usable after failures
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	large := bytes.Repeat([]byte("0123456789"), 100_000)
+	fake.Put("large", 0, large)
+
+	// The rest of the value is drained after the writer fails
+	w := &failingWriter{limit: 10_000}
+	_, _, err = cli.GetTo(ctx, "large", w)
+	s.ErrorIs(err, errWriter)
+
+	val, err := cli.GetV(ctx, "large")
+	s.Require().NoError(err)
+	s.Equal(large, val)
+
+	// A short value is rejected by the server
+	err = cli.SetFrom(ctx, "short", bytes.NewReader(large), int64(len(large))+1, 0, 0)
+	s.ErrorIs(err, io.ErrUnexpectedEOF)
+	_, _, ok := fake.Get("short")
+	s.False(ok)
+	s.Error(cli.SetFrom(ctx, "negative", bytes.NewReader(large), -1, 0, 0))
+
+	s.Require().NoError(cli.SetV(ctx, "foo", []byte("bar")))
+	val, err = cli.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Equal("bar", string(val))
+}
+
+// blockingReader blocks until unblocked
+type blockingReader struct {
+	unblock chan struct{}
+}
+
+func (r blockingReader) Read([]byte) (int, error) {
+	<-r.unblock
+	return 0, io.EOF
+}
+
+func (s *StreamSuite) TestBlockingReader() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	r := blockingReader{unblock: make(chan struct{})}
+	defer close(r.unblock)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = cli.SetFrom(ctx, "foo", r, 10, 0, 0)
+	s.ErrorIs(err, context.DeadlineExceeded)
+	s.Less(time.Since(start), time.Second)
+
+	// The blocked connection is closed, and replaced
+	s.Require().NoError(cli.SetV(context.Background(), "foo", []byte("bar")))
+	val, err := cli.GetV(context.Background(), "foo")
+	s.Require().NoError(err)
+	s.Equal("bar", string(val))
+}
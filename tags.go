@@ -0,0 +1,302 @@
+package memcached_go
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"memcached-go/mmc"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FlagTagged marks tagged values by default, see Tagging.Flag.
+const FlagTagged uint16 = 1 << 11
+
+var (
+	errTaggingDisabled = errors.New("tagging is not enabled, see WithTagging")
+	errBadTags         = errors.New("bad tags of a tagged value")
+)
+
+// Tagging configures tag-based invalidation. Every tag has a version stored in memcached, tagged values are stored
+// with the versions of their tags at the time of the write, and are valid only while these are still current.
+// InvalidateTag increments the version, so that all values carrying the tag become misses. Get doesn't know the tags of
+// a value before reading it, so it takes a second round trip to fetch their versions. GetTagged fetches the value and
+// the versions of the tags passed by the caller in a single multi-key get.
+type Tagging struct {
+	// Flag is the reserved bit marking tagged values, FlagTagged if zero
+	Flag uint16
+}
+
+// WithTagging enables SetTagged and InvalidateTag, Get validates the tags. Flags passed to Set must not use the
+// reserved bit.
+func WithTagging(cfg Tagging) Option {
+	return func(c *Client) {
+		if cfg.Flag == 0 {
+			cfg.Flag = FlagTagged
+		}
+		c.tagging = &cfg
+		c.reserveFlag("tagging", cfg.Flag)
+	}
+}
+
+// SetTagged stores the value with tags, e.g. product:42, which InvalidateTag can later invalidate it by.
+func (c *Client) SetTagged(
+	ctx context.Context, key string, flags uint16, val []byte, ttl time.Duration, tags ...string,
+) error {
+	if c.tagging == nil {
+		return errTaggingDisabled
+	}
+	key, err := c.key(key)
+	if err != nil {
+		return err
+	}
+	if flags&c.reservedFlags != 0 {
+		return ErrReservedFlags
+	}
+	tagKeys, err := c.tagKeys(tags)
+	if err != nil {
+		return err
+	}
+
+	versions, err := c.tagVersions(ctx, tagKeys)
+	if err != nil {
+		return err
+	}
+	// The version is missing if the tag was never used, or was evicted, either way the values are already invalid
+	for _, tagKey := range tagKeys {
+		if _, ok := versions[tagKey]; !ok {
+			versions[tagKey], err = c.createTagVersion(ctx, tagKey)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	wrapped := binary.AppendUvarint(nil, uint64(len(tagKeys)))
+	for _, tagKey := range tagKeys {
+		wrapped = binary.AppendUvarint(wrapped, uint64(len(tagKey)))
+		wrapped = append(wrapped, tagKey...)
+		wrapped = binary.AppendUvarint(wrapped, versions[tagKey])
+	}
+	wrapped = append(wrapped, val...)
+	return c.store(ctx, key, flags|c.tagging.Flag, wrapped, ttl)
+}
+
+// InvalidateTag makes all values carrying the tag misses.
+func (c *Client) InvalidateTag(ctx context.Context, tag string) error {
+	if c.tagging == nil {
+		return errTaggingDisabled
+	}
+	tagKeys, err := c.tagKeys([]string{tag})
+	if err != nil {
+		return err
+	}
+	incrMsg := mmc.NewIncr(tagKeys[0], 1)
+	err = c.call(ctx, OpIncr, tagKeys[0], incrMsg)
+	if err != nil {
+		return err
+	}
+	if errors.Is(incrMsg.Error, mmc.ErrNotFound) {
+		// The values carrying the tag are invalid already
+		return nil
+	}
+	return incrMsg.Error
+}
+
+// GetTagged is like Get, for a value stored with the given tags, fetched along with the versions of the tags in a single
+// multi-key get. Tags of the value which aren't passed take another round trip. The near cache and batching don't apply.
+func (c *Client) GetTagged(ctx context.Context, key string, tags ...string) ([]byte, uint16, error) {
+	if c.tagging == nil {
+		return nil, 0, errTaggingDisabled
+	}
+	key, err := c.key(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	tagKeys, err := c.tagKeys(tags)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	items, err := c.getMulti(ctx, append([]string{key}, tagKeys...))
+	if err != nil {
+		return nil, 0, err
+	}
+	item, ok := items[key]
+	if !ok {
+		return nil, 0, nil
+	}
+	delete(items, key)
+	versions, err := parseVersions(items)
+	if err != nil {
+		return nil, 0, err
+	}
+	// Versions missing from the response are invalid, rather than unknown
+	for _, tagKey := range tagKeys {
+		if _, ok := versions[tagKey]; !ok {
+			versions[tagKey] = 0
+		}
+	}
+
+	val, flags, err := c.decode(ctx, key, item.Value, item.Flags)
+	if err != nil || val == nil {
+		return nil, 0, err
+	}
+	return c.unwrap(ctx, key, val, flags, versions)
+}
+
+// tagKeys returns the transformed keys of tag versions.
+func (c *Client) tagKeys(tags []string) ([]string, error) {
+	keys := make([]string, len(tags))
+	for i, tag := range tags {
+		var err error
+		keys[i], err = c.key("tag:" + tag)
+		if err != nil {
+			return nil, fmt.Errorf("tag %s: %w", tag, err)
+		}
+	}
+	return keys, nil
+}
+
+func (c *Client) tagVersions(ctx context.Context, tagKeys []string) (map[string]uint64, error) {
+	items, err := c.getMulti(ctx, tagKeys)
+	if err != nil {
+		return nil, err
+	}
+	return parseVersions(items)
+}
+
+// parseTags splits a tagged value into the keys of its tags, their versions when it was stored, and the value.
+func parseTags(key string, wrapped []byte) ([]string, []uint64, []byte, error) {
+	count, n := binary.Uvarint(wrapped)
+	if n <= 0 || count > uint64(len(wrapped)) {
+		return nil, nil, nil, fmt.Errorf("get %s: %w", key, errBadTags)
+	}
+	wrapped = wrapped[n:]
+	tagKeys := make([]string, count)
+	stored := make([]uint64, count)
+	for i := range tagKeys {
+		length, n := binary.Uvarint(wrapped)
+		if n <= 0 || length > uint64(len(wrapped)-n) {
+			return nil, nil, nil, fmt.Errorf("get %s: %w", key, errBadTags)
+		}
+		tagKeys[i] = string(wrapped[n : n+int(length)])
+		wrapped = wrapped[n+int(length):]
+		stored[i], n = binary.Uvarint(wrapped)
+		if n <= 0 {
+			return nil, nil, nil, fmt.Errorf("get %s: %w", key, errBadTags)
+		}
+		wrapped = wrapped[n:]
+	}
+	return tagKeys, stored, wrapped, nil
+}
+
+// multiTagVersions fetches the versions of the tags of all tagged values in a single request. Missing versions are
+// set to 0, which is never current.
+func (c *Client) multiTagVersions(ctx context.Context, items map[string]mmc.Item) (map[string]uint64, error) {
+	versions := map[string]uint64{}
+	var tagKeys []string
+	for key, item := range items {
+		if item.Flags&c.tagging.Flag == 0 {
+			continue
+		}
+		itemTags, _, _, err := parseTags(key, item.Value)
+		if err != nil {
+			return nil, err
+		}
+		for _, tagKey := range itemTags {
+			if _, ok := versions[tagKey]; !ok {
+				versions[tagKey] = 0
+				tagKeys = append(tagKeys, tagKey)
+			}
+		}
+	}
+	if len(tagKeys) == 0 {
+		return versions, nil
+	}
+	fetched, err := c.tagVersions(ctx, tagKeys)
+	if err != nil {
+		return nil, err
+	}
+	for tagKey, version := range fetched {
+		versions[tagKey] = version
+	}
+	return versions, nil
+}
+
+func parseVersions(items map[string]mmc.Item) (map[string]uint64, error) {
+	versions := make(map[string]uint64, len(items))
+	for tagKey, item := range items {
+		// Counters may be padded with spaces
+		version, err := strconv.ParseUint(strings.TrimSpace(string(item.Value)), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q of %s: %w", string(item.Value), tagKey, err)
+		}
+		versions[tagKey] = version
+	}
+	return versions, nil
+}
+
+// createTagVersion starts versions from the current time, rather than from 1, so that if the version gets evicted,
+// values with earlier versions don't become valid again.
+func (c *Client) createTagVersion(ctx context.Context, tagKey string) (uint64, error) {
+	version := uint64(time.Now().UnixNano())
+	addMsg := mmc.NewAdd(tagKey, 0, strconv.AppendUint(nil, version, 10), 0)
+	err := c.call(ctx, OpAdd, tagKey, addMsg)
+	if err != nil {
+		return 0, err
+	}
+	if errors.Is(addMsg.Error, mmc.ErrNotStored) {
+		// Created concurrently
+		versions, err := c.tagVersions(ctx, []string{tagKey})
+		if err != nil {
+			return 0, err
+		}
+		version, ok := versions[tagKey]
+		if !ok {
+			return 0, fmt.Errorf("version of %s evicted right after it was created", tagKey)
+		}
+		return version, nil
+	}
+	return version, addMsg.Error
+}
+
+// checkTags unwraps a tagged value, which is valid only if the versions of its tags are still current. Only the versions
+// which aren't known already are fetched.
+func (c *Client) checkTags(
+	ctx context.Context, key string, wrapped []byte, known map[string]uint64,
+) ([]byte, bool, error) {
+	tagKeys, stored, wrapped, err := parseTags(key, wrapped)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(tagKeys) == 0 {
+		return wrapped, true, nil
+	}
+
+	var missing []string
+	for _, tagKey := range tagKeys {
+		if _, ok := known[tagKey]; !ok {
+			missing = append(missing, tagKey)
+		}
+	}
+	versions := known
+	if len(missing) > 0 {
+		fetched, err := c.tagVersions(ctx, missing)
+		if err != nil {
+			return nil, false, err
+		}
+		for tagKey, version := range known {
+			fetched[tagKey] = version
+		}
+		versions = fetched
+	}
+	for i, tagKey := range tagKeys {
+		if versions[tagKey] != stored[i] {
+			return nil, false, nil
+		}
+	}
+	return wrapped, true, nil
+}
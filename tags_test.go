@@ -0,0 +1,159 @@
+package memcached_go
+
+import (
+	"context"
+	"memcached-go/testutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TagsSuite struct {
+	testutil.BaseSuite
+}
+
+func TestTagsSuite(t *testing.T) {
+	suite.Run(t, new(TagsSuite))
+}
+
+func (s *TagsSuite) TestInvalidateTag() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1, WithTagging(Tagging{}), WithNearCache(NearCache{MaxBytes: 1 << 20}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	s.Require().NoError(cli.SetTagged(ctx, "product:42", 3, []byte("phone"), time.Hour, "product:42", "category:7"))
+	s.Require().NoError(cli.SetTagged(ctx, "product:43", 0, []byte("laptop"), time.Hour, "category:8"))
+	s.Require().NoError(cli.SetTagged(ctx, "untagged", 0, []byte("foo"), time.Hour))
+
+	val, flags, err := cli.Get(ctx, "product:42")
+	s.Require().NoError(err)
+	s.Equal("phone", string(val))
+	s.Equal(uint16(3), flags)
+
+	s.Require().NoError(cli.InvalidateTag(ctx, "category:7"))
+	// Invalid for the near cache too
+	val, err = cli.GetV(ctx, "product:42")
+	s.Require().NoError(err)
+	s.Nil(val)
+
+	val, err = cli.GetV(ctx, "product:43")
+	s.Require().NoError(err)
+	s.Equal("laptop", string(val))
+	val, err = cli.GetV(ctx, "untagged")
+	s.Require().NoError(err)
+	s.Equal("foo", string(val))
+
+	// Written again, with the current version
+	s.Require().NoError(cli.SetTagged(ctx, "product:42", 0, []byte("phone 2"), time.Hour, "category:7"))
+	val, err = cli.GetV(ctx, "product:42")
+	s.Require().NoError(err)
+	s.Equal("phone 2", string(val))
+
+	// Tags which were never used have nothing to invalidate
+	s.NoError(cli.InvalidateTag(ctx, "category:9"))
+	s.ErrorIs(cli.Set(ctx, "foo", FlagTagged, []byte("bar"), 0), ErrReservedFlags)
+}
+
+func (s *TagsSuite) TestEvictedVersion() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1, WithTagging(Tagging{}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	s.Require().NoError(cli.SetTagged(ctx, "foo", 0, []byte("bar"), time.Hour, "baz"))
+	s.Require().NoError(cli.Delete(ctx, "tag:baz"))
+
+	// Values with versions lost to eviction are misses, even once the tag is used again
+	val, err := cli.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Nil(val)
+	s.Require().NoError(cli.SetTagged(ctx, "other", 0, []byte("qux"), time.Hour, "baz"))
+	val, err = cli.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Nil(val)
+	val, err = cli.GetV(ctx, "other")
+	s.Require().NoError(err)
+	s.Equal("qux", string(val))
+}
+
+func (s *TagsSuite) TestDisabled() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	s.ErrorIs(cli.SetTagged(context.Background(), "foo", 0, []byte("bar"), 0, "baz"), errTaggingDisabled)
+	s.ErrorIs(cli.InvalidateTag(context.Background(), "baz"), errTaggingDisabled)
+}
+
+func (s *TagsSuite) TestGetTagged() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1, WithTagging(Tagging{}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	s.Require().NoError(cli.SetTagged(ctx, "product:42", 3, []byte("phone"), time.Hour, "product:42", "category:7"))
+
+	// The value and the versions of its tags in a single round trip
+	commands := fake.Commands()
+	val, flags, err := cli.GetTagged(ctx, "product:42", "product:42", "category:7")
+	s.Require().NoError(err)
+	s.Equal("phone", string(val))
+	s.Equal(uint16(3), flags)
+	s.Equal(commands+1, fake.Commands())
+
+	// Tags which aren't passed are fetched separately
+	s.Require().NoError(cli.InvalidateTag(ctx, "category:7"))
+	val, _, err = cli.GetTagged(ctx, "product:42", "product:42")
+	s.Require().NoError(err)
+	s.Nil(val)
+	val, _, err = cli.GetTagged(ctx, "product:42", "category:7")
+	s.Require().NoError(err)
+	s.Nil(val)
+
+	val, _, err = cli.GetTagged(ctx, "missing", "category:7")
+	s.Require().NoError(err)
+	s.Nil(val)
+}
+
+func (s *TagsSuite) TestGetOrLoad() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1, WithTagging(Tagging{}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	s.Require().NoError(cli.SetTagged(ctx, "foo", 0, []byte("bar"), time.Hour, "baz"))
+	loader := func(ctx context.Context) ([]byte, error) {
+		return []byte("loaded"), nil
+	}
+
+	val, err := cli.GetOrLoad(ctx, "foo", time.Hour, loader)
+	s.Require().NoError(err)
+	s.Equal("bar", string(val))
+
+	s.Require().NoError(cli.InvalidateTag(ctx, "baz"))
+	val, err = cli.GetOrLoad(ctx, "foo", time.Hour, loader)
+	s.Require().NoError(err)
+	s.Equal("loaded", string(val))
+}
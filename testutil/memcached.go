@@ -0,0 +1,446 @@
+package testutil
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FakeMemcached is a minimal in-memory memcached speaking the text protocol, meant for tests only.
+// It supports enough commands for the client features, and can inject failures.
+type FakeMemcached struct {
+	listener net.Listener
+
+	lock  sync.Mutex
+	items map[string]*fakeItem
+	cas   uint64
+	conns map[net.Conn]struct{}
+
+	// Number of upcoming commands after which the connection is dropped instead of responding
+	drops atomic.Int32
+	// Number of upcoming commands answered with a server error
+	fails atomic.Int32
+	// Delay before processing each command
+	delay atomic.Int64
+	// Number of commands processed
+	commands atomic.Int64
+}
+
+type fakeItem struct {
+	flags   uint32
+	value   []byte
+	cas     uint64
+	expires time.Time
+}
+
+func NewFakeMemcached() (*FakeMemcached, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	f := &FakeMemcached{
+		listener: listener,
+		items:    map[string]*fakeItem{},
+		conns:    map[net.Conn]struct{}{},
+	}
+	go f.accept()
+	return f, nil
+}
+
+func (f *FakeMemcached) Addr() string {
+	return f.listener.Addr().String()
+}
+
+func (f *FakeMemcached) Close() {
+	_ = f.listener.Close()
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for conn := range f.conns {
+		_ = conn.Close()
+	}
+}
+
+// DropConnections makes the server close the connection instead of responding to the next n commands.
+func (f *FakeMemcached) DropConnections(n int) {
+	f.drops.Store(int32(n))
+}
+
+// FailCommands makes the server answer the next n commands with SERVER_ERROR. Data blocks of storage commands are not
+// consumed, so it's meant for retrievals and deletes.
+func (f *FakeMemcached) FailCommands(n int) {
+	f.fails.Store(int32(n))
+}
+
+// SetDelay delays processing of every command.
+func (f *FakeMemcached) SetDelay(d time.Duration) {
+	f.delay.Store(int64(d))
+}
+
+// Commands returns the number of commands processed so far.
+func (f *FakeMemcached) Commands() int {
+	return int(f.commands.Load())
+}
+
+// Get returns a stored value, bypassing the protocol.
+func (f *FakeMemcached) Get(key string) ([]byte, uint32, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	item, ok := f.item(key)
+	if !ok {
+		return nil, 0, false
+	}
+	return item.value, item.flags, true
+}
+
+// Put stores a value, bypassing the protocol.
+func (f *FakeMemcached) Put(key string, flags uint32, value []byte) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.cas++
+	f.items[key] = &fakeItem{flags: flags, value: value, cas: f.cas}
+}
+
+func (f *FakeMemcached) accept() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		f.lock.Lock()
+		f.conns[conn] = struct{}{}
+		f.lock.Unlock()
+		go f.serve(conn)
+	}
+}
+
+func (f *FakeMemcached) serve(conn net.Conn) {
+	defer func() {
+		f.lock.Lock()
+		delete(f.conns, conn)
+		f.lock.Unlock()
+		_ = conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if d := time.Duration(f.delay.Load()); d > 0 {
+			time.Sleep(d)
+		}
+		if f.drops.Add(-1) >= 0 {
+			return
+		}
+		f.commands.Add(1)
+		if f.fails.Add(-1) >= 0 {
+			if _, err := w.WriteString("SERVER_ERROR out of memory\r\n"); err != nil {
+				return
+			}
+		} else if err := f.handle(strings.Fields(line), r, w); err != nil {
+			return
+		}
+		// Responses are flushed only once there is no more pipelined input, like memcached does
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (f *FakeMemcached) handle(args []string, r *bufio.Reader, w *bufio.Writer) error {
+	if len(args) == 0 {
+		_, err := w.WriteString("ERROR\r\n")
+		return err
+	}
+
+	if args[len(args)-1] == "noreply" {
+		return f.handleNoReply(args[:len(args)-1], r, w)
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	switch args[0] {
+	case "get", "gets":
+		for _, key := range args[1:] {
+			item, ok := f.item(key)
+			if !ok {
+				continue
+			}
+			if args[0] == "gets" {
+				_, _ = fmt.Fprintf(w, "VALUE %s %d %d %d\r\n", key, item.flags, len(item.value), item.cas)
+			} else {
+				_, _ = fmt.Fprintf(w, "VALUE %s %d %d\r\n", key, item.flags, len(item.value))
+			}
+			_, _ = w.Write(item.value)
+			_, _ = w.WriteString("\r\n")
+		}
+		_, err := w.WriteString("END\r\n")
+		return err
+
+	case "set", "add", "cas":
+		return f.store(args, r, w)
+
+	case "delete":
+		if len(args) != 2 {
+			return f.clientError(w, "bad command line format")
+		}
+		if _, ok := f.item(args[1]); !ok {
+			_, err := w.WriteString("NOT_FOUND\r\n")
+			return err
+		}
+		delete(f.items, args[1])
+		_, err := w.WriteString("DELETED\r\n")
+		return err
+
+	case "incr", "decr":
+		if len(args) != 3 {
+			return f.clientError(w, "bad command line format")
+		}
+		delta, err := strconv.ParseUint(args[2], 10, 64)
+		if err != nil {
+			return f.clientError(w, "invalid numeric delta argument")
+		}
+		item, ok := f.item(args[1])
+		if !ok {
+			_, err := w.WriteString("NOT_FOUND\r\n")
+			return err
+		}
+		current, err := strconv.ParseUint(string(item.value), 10, 64)
+		if err != nil {
+			return f.clientError(w, "cannot increment or decrement non-numeric value")
+		}
+		if args[0] == "incr" {
+			current += delta
+		} else if delta > current {
+			current = 0
+		} else {
+			current -= delta
+		}
+		f.cas++
+		item.value = []byte(strconv.FormatUint(current, 10))
+		item.cas = f.cas
+		_, err = fmt.Fprintf(w, "%d\r\n", current)
+		return err
+
+	case "touch":
+		if len(args) != 3 {
+			return f.clientError(w, "bad command line format")
+		}
+		exptime, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return f.clientError(w, "invalid exptime argument")
+		}
+		item, ok := f.item(args[1])
+		if !ok {
+			_, err := w.WriteString("NOT_FOUND\r\n")
+			return err
+		}
+		item.expires = expiresAt(exptime)
+		_, err = w.WriteString("TOUCHED\r\n")
+		return err
+
+	case "ms", "md":
+		return f.meta(args, r, w)
+
+	case "mn":
+		_, err := w.WriteString("MN\r\n")
+		return err
+
+	default:
+		_, err := w.WriteString("ERROR\r\n")
+		return err
+	}
+}
+
+// handleNoReply suppresses the response, except for errors, which memcached sends even for noreply commands.
+func (f *FakeMemcached) handleNoReply(args []string, r *bufio.Reader, w *bufio.Writer) error {
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	if err := f.handle(args, r, bw); err != nil {
+		return err
+	}
+	_ = bw.Flush()
+	if !bytes.Contains(buf.Bytes(), []byte("ERROR")) {
+		return nil
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (f *FakeMemcached) store(args []string, r *bufio.Reader, w *bufio.Writer) error {
+	// <cmd> <key> <flags> <exptime> <bytes> [<cas unique>]
+	expected := 5
+	if args[0] == "cas" {
+		expected = 6
+	}
+	if len(args) != expected {
+		return f.clientError(w, "bad command line format")
+	}
+	key := args[1]
+	flags, err1 := strconv.ParseUint(args[2], 10, 32)
+	exptime, err2 := strconv.ParseInt(args[3], 10, 64)
+	length, err3 := strconv.ParseUint(args[4], 10, 32)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return f.clientError(w, "bad command line format")
+	}
+	if len(key) > 250 {
+		return f.clientError(w, "bad command line format")
+	}
+
+	data := make([]byte, length+2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	if !bytes.HasSuffix(data, []byte("\r\n")) {
+		return f.clientError(w, "bad data chunk")
+	}
+	data = data[:length]
+
+	existing, exists := f.item(key)
+	switch args[0] {
+	case "add":
+		if exists {
+			_, err := w.WriteString("NOT_STORED\r\n")
+			return err
+		}
+	case "cas":
+		unique, err := strconv.ParseUint(args[5], 10, 64)
+		if err != nil {
+			return f.clientError(w, "bad command line format")
+		}
+		if !exists {
+			_, err := w.WriteString("NOT_FOUND\r\n")
+			return err
+		}
+		if existing.cas != unique {
+			_, err := w.WriteString("EXISTS\r\n")
+			return err
+		}
+	}
+
+	f.cas++
+	f.items[key] = &fakeItem{flags: uint32(flags), value: data, cas: f.cas, expires: expiresAt(exptime)}
+	_, err := w.WriteString("STORED\r\n")
+	return err
+}
+
+// meta handles meta set and delete with the flags used by the client: F, T, C, q and O.
+func (f *FakeMemcached) meta(args []string, r *bufio.Reader, w *bufio.Writer) error {
+	isSet := args[0] == "ms"
+	minArgs := 2
+	if isSet {
+		minArgs = 3
+	}
+	if len(args) < minArgs {
+		return f.clientError(w, "bad command line format")
+	}
+
+	key := args[1]
+	var flags uint64
+	var exptime int64
+	var cas uint64
+	var quiet bool
+	var opaque string
+	for _, flag := range args[minArgs:] {
+		var err error
+		switch flag[0] {
+		case 'F':
+			flags, err = strconv.ParseUint(flag[1:], 10, 32)
+		case 'T':
+			exptime, err = strconv.ParseInt(flag[1:], 10, 64)
+		case 'C':
+			cas, err = strconv.ParseUint(flag[1:], 10, 64)
+		case 'q':
+			quiet = true
+		case 'O':
+			opaque = " " + flag
+		}
+		if err != nil {
+			return f.clientError(w, "bad token in command line format")
+		}
+	}
+
+	respond := func(code string) error {
+		if quiet && (code == "HD" || (!isSet && code == "NF")) {
+			return nil
+		}
+		_, err := w.WriteString(code + opaque + "\r\n")
+		return err
+	}
+
+	existing, exists := f.item(key)
+	if !isSet {
+		if !exists {
+			return respond("NF")
+		}
+		if cas != 0 && existing.cas != cas {
+			return respond("EX")
+		}
+		delete(f.items, key)
+		return respond("HD")
+	}
+
+	length, err := strconv.ParseUint(args[2], 10, 32)
+	if err != nil {
+		return f.clientError(w, "bad data chunk")
+	}
+	data := make([]byte, length+2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	if !bytes.HasSuffix(data, []byte("\r\n")) {
+		return f.clientError(w, "bad data chunk")
+	}
+	if cas != 0 && !exists {
+		return respond("NF")
+	}
+	if cas != 0 && existing.cas != cas {
+		return respond("EX")
+	}
+	f.cas++
+	f.items[key] = &fakeItem{flags: uint32(flags), value: data[:length], cas: f.cas, expires: expiresAt(exptime)}
+	return respond("HD")
+}
+
+func (f *FakeMemcached) clientError(w *bufio.Writer, msg string) error {
+	_, err := fmt.Fprintf(w, "CLIENT_ERROR %s\r\n", msg)
+	return err
+}
+
+// item returns a live item, expired items are removed. Must be called with the lock held.
+func (f *FakeMemcached) item(key string) (*fakeItem, bool) {
+	item, ok := f.items[key]
+	if !ok {
+		return nil, false
+	}
+	if !item.expires.IsZero() && !time.Now().Before(item.expires) {
+		delete(f.items, key)
+		return nil, false
+	}
+	return item, true
+}
+
+func expiresAt(exptime int64) time.Time {
+	switch {
+	case exptime == 0:
+		return time.Time{}
+	case exptime < 0:
+		return time.Now()
+	case exptime > int64(30*24*time.Hour/time.Second):
+		return time.Unix(exptime, 0)
+	default:
+		return time.Now().Add(time.Duration(exptime) * time.Second)
+	}
+}
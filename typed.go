@@ -0,0 +1,44 @@
+package memcached_go
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// The codec ID is stored in the low byte of the flags
+const codecFlags uint16 = 0xff
+
+// Typed is a cache of values of a single type, serialized with a codec.
+type Typed[T any] struct {
+	cli   *Client
+	codec Codec[T]
+}
+
+func NewTyped[T any](cli *Client, codec Codec[T]) *Typed[T] {
+	return &Typed[T]{cli: cli, codec: codec}
+}
+
+// Get returns false on miss. Values written with another codec fail with ErrCodecMismatch.
+func (t *Typed[T]) Get(ctx context.Context, key string) (T, bool, error) {
+	var v T
+	data, flags, err := t.cli.Get(ctx, key)
+	if err != nil || data == nil {
+		return v, false, err
+	}
+	if id := uint8(flags & codecFlags); id != t.codec.ID() {
+		return v, false, fmt.Errorf("get %s: codec %d, expected %d: %w", key, id, t.codec.ID(), ErrCodecMismatch)
+	}
+	if err := t.codec.Unmarshal(data, &v); err != nil {
+		return v, false, fmt.Errorf("unmarshal %s: %w", key, err)
+	}
+	return v, true, nil
+}
+
+func (t *Typed[T]) Set(ctx context.Context, key string, v T, ttl time.Duration) error {
+	data, err := t.codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", key, err)
+	}
+	return t.cli.Set(ctx, key, uint16(t.codec.ID()), data, ttl)
+}
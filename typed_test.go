@@ -0,0 +1,80 @@
+package memcached_go
+
+import (
+	"context"
+	"memcached-go/testutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TypedSuite struct {
+	testutil.BaseSuite
+}
+
+func TestTypedSuite(t *testing.T) {
+	suite.Run(t, new(TypedSuite))
+}
+
+type user struct {
+	Name  string
+	Admin bool
+}
+
+func (s *TypedSuite) TestCodecs() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	alice := user{Name: "alice", Admin: true}
+	for _, codec := range []Codec[user]{JSONCodec[user]{}, GobCodec[user]{}} {
+		users := NewTyped(cli, codec)
+		s.Require().NoError(users.Set(ctx, "alice", alice, time.Minute))
+		_, flags, _ := fake.Get("alice")
+		s.Equal(uint32(codec.ID()), flags)
+
+		read, ok, err := users.Get(ctx, "alice")
+		s.Require().NoError(err)
+		s.True(ok)
+		s.Equal(alice, read)
+
+		_, ok, err = users.Get(ctx, "bob")
+		s.Require().NoError(err)
+		s.False(ok)
+	}
+
+	times := NewTyped(cli, BinaryCodec[time.Time, *time.Time]{})
+	now := time.Now().Round(0)
+	s.Require().NoError(times.Set(ctx, "now", now, time.Minute))
+	read, ok, err := times.Get(ctx, "now")
+	s.Require().NoError(err)
+	s.True(ok)
+	s.True(now.Equal(read))
+}
+
+func (s *TypedSuite) TestCodecMismatch() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1)
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	s.Require().NoError(NewTyped[user](cli, GobCodec[user]{}).Set(ctx, "alice", user{Name: "alice"}, 0))
+	_, ok, err := NewTyped[user](cli, JSONCodec[user]{}).Get(ctx, "alice")
+	s.ErrorIs(err, ErrCodecMismatch)
+	s.False(ok)
+
+	// Raw values have no codec
+	s.Require().NoError(cli.SetV(ctx, "raw", []byte(`{"Name":"bob"}`)))
+	_, _, err = NewTyped[user](cli, JSONCodec[user]{}).Get(ctx, "raw")
+	s.ErrorIs(err, ErrCodecMismatch)
+}
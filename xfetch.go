@@ -0,0 +1,89 @@
+package memcached_go
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// FlagEnvelope marks enveloped values by default, see EarlyRefresh.Flag.
+const FlagEnvelope uint16 = 1 << 12
+
+const (
+	envelopeVersion    = 1
+	envelopeHeaderSize = 1 + 8 + 8
+)
+
+var errBadEnvelope = errors.New("bad envelope")
+
+// EarlyRefresh configures probabilistic early recomputation (XFetch) of values cached by GetOrLoad, to avoid
+// stampedes when popular values expire. Values are stored in an envelope with their logical expiry and the time it
+// took to load them, and each read refreshes the value early with a probability growing as the expiry approaches,
+// and with the load time. Values without an envelope, e.g. written by Set, are read as is.
+type EarlyRefresh struct {
+	// Beta above 1 favors earlier refreshes, below 1 later ones, 1 if zero
+	Beta float64
+	// Flag is the reserved bit marking enveloped values, FlagEnvelope if zero
+	Flag uint16
+}
+
+// WithEarlyRefresh makes GetOrLoad store values in envelopes and refresh them early, Get unwraps them. Flags passed to
+// Set must not use the reserved bit.
+func WithEarlyRefresh(cfg EarlyRefresh) Option {
+	return func(c *Client) {
+		if cfg.Beta <= 0 {
+			cfg.Beta = 1
+		}
+		if cfg.Flag == 0 {
+			cfg.Flag = FlagEnvelope
+		}
+		c.refresh = &cfg
+		c.reserveFlag("early refresh", cfg.Flag)
+	}
+}
+
+// envelope holds what XFetch needs besides the value.
+type envelope struct {
+	// Zero if the value doesn't expire
+	expires time.Time
+	// How long it took to load the value
+	delta time.Duration
+}
+
+func (e envelope) wrap(val []byte) []byte {
+	wrapped := make([]byte, envelopeHeaderSize, envelopeHeaderSize+len(val))
+	wrapped[0] = envelopeVersion
+	if !e.expires.IsZero() {
+		binary.BigEndian.PutUint64(wrapped[1:], uint64(e.expires.UnixNano()))
+	}
+	binary.BigEndian.PutUint64(wrapped[9:], uint64(e.delta))
+	return append(wrapped, val...)
+}
+
+func unwrapEnvelope(wrapped []byte) (envelope, []byte, error) {
+	if len(wrapped) < envelopeHeaderSize || wrapped[0] != envelopeVersion {
+		return envelope{}, nil, errBadEnvelope
+	}
+	e := envelope{delta: time.Duration(binary.BigEndian.Uint64(wrapped[9:]))}
+	if expires := int64(binary.BigEndian.Uint64(wrapped[1:])); expires != 0 {
+		e.expires = time.Unix(0, expires)
+	}
+	return e, wrapped[envelopeHeaderSize:], nil
+}
+
+// refreshNow decides whether to refresh at now, rnd is uniformly distributed in (0, 1].
+// It's the XFetch condition: now - delta * beta * ln(rnd) >= expiry.
+func (e envelope) refreshNow(now time.Time, beta, rnd float64) bool {
+	if e.expires.IsZero() {
+		return false
+	}
+	early := time.Duration(-float64(e.delta) * beta * math.Log(rnd))
+	return !now.Add(early).Before(e.expires)
+}
+
+// shouldRefresh draws whether the value should be refreshed early.
+func (c *Client) shouldRefresh(e envelope) bool {
+	return e.refreshNow(time.Now(), c.refresh.Beta, 1-rand.Float64())
+}
@@ -0,0 +1,100 @@
+package memcached_go
+
+import (
+	"context"
+	"memcached-go/testutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type EarlyRefreshSuite struct {
+	testutil.BaseSuite
+}
+
+func TestEarlyRefreshSuite(t *testing.T) {
+	suite.Run(t, new(EarlyRefreshSuite))
+}
+
+func (s *EarlyRefreshSuite) TestEnvelope() {
+	e := envelope{expires: time.Unix(100, 5), delta: time.Second}
+	unwrapped, val, err := unwrapEnvelope(e.wrap([]byte("value")))
+	s.Require().NoError(err)
+	s.Equal("value", string(val))
+	s.True(e.expires.Equal(unwrapped.expires))
+	s.Equal(e.delta, unwrapped.delta)
+
+	_, _, err = unwrapEnvelope([]byte("legacy"))
+	s.ErrorIs(err, errBadEnvelope)
+
+	// Never refreshed without expiry
+	unwrapped, _, err = unwrapEnvelope(envelope{delta: time.Hour}.wrap(nil))
+	s.Require().NoError(err)
+	s.True(unwrapped.expires.IsZero())
+	s.False(unwrapped.refreshNow(time.Now(), 1, 0.001))
+}
+
+func (s *EarlyRefreshSuite) TestRefreshNow() {
+	now := time.Now()
+	e := envelope{expires: now.Add(time.Minute), delta: 10 * time.Second}
+
+	// Refreshed once -ln(rnd) * delta * beta reaches the expiry, i.e. for rnd <= exp(-6)
+	s.False(e.refreshNow(now, 1, 0.5))
+	s.False(e.refreshNow(now, 1, 0.003))
+	s.True(e.refreshNow(now, 1, 0.002))
+	// Earlier with a higher beta
+	s.True(e.refreshNow(now, 3, 0.1))
+	// Closer to the expiry
+	s.True(e.refreshNow(now.Add(55*time.Second), 1, 0.5))
+	s.True(e.refreshNow(now.Add(time.Minute), 1, 1))
+}
+
+func (s *EarlyRefreshSuite) TestGetOrLoad() {
+	fake, err := testutil.NewFakeMemcached()
+	s.Require().NoError(err)
+	defer fake.Close()
+
+	cli, err := NewClient(fake.Addr(), 1, 1, WithEarlyRefresh(EarlyRefresh{}))
+	s.Require().NoError(err)
+	defer cli.Close()
+
+	ctx := context.Background()
+	var loads int
+	loader := func(ctx context.Context) ([]byte, error) {
+		loads++
+		return []byte("loaded"), nil
+	}
+
+	val, err := cli.GetOrLoad(ctx, "foo", time.Hour, loader)
+	s.Require().NoError(err)
+	s.Equal("loaded", string(val))
+	_, flags, _ := fake.Get("foo")
+	s.Equal(uint32(FlagEnvelope), flags)
+
+	// Far from the expiry, and quick to load
+	val, err = cli.GetOrLoad(ctx, "foo", time.Hour, loader)
+	s.Require().NoError(err)
+	s.Equal("loaded", string(val))
+	s.Equal(1, loads)
+
+	// Plain gets unwrap the value
+	val, err = cli.GetV(ctx, "foo")
+	s.Require().NoError(err)
+	s.Equal("loaded", string(val))
+
+	// Logically expired, but still stored
+	expired := envelope{expires: time.Now().Add(-time.Second), delta: time.Millisecond}
+	fake.Put("foo", uint32(FlagEnvelope), expired.wrap([]byte("stale")))
+	val, err = cli.GetOrLoad(ctx, "foo", time.Hour, loader)
+	s.Require().NoError(err)
+	s.Equal("loaded", string(val))
+	s.Equal(2, loads)
+
+	// Values without an envelope are read as is
+	s.Require().NoError(cli.SetV(ctx, "legacy", []byte("legacy")))
+	val, err = cli.GetOrLoad(ctx, "legacy", time.Hour, loader)
+	s.Require().NoError(err)
+	s.Equal("legacy", string(val))
+	s.Equal(2, loads)
+}